@@ -0,0 +1,39 @@
+package assets
+
+/*
+    Load replacement icons from disk, so themes can change how j3 looks
+    without recompiling it.
+*/
+
+import (
+    imglib "image"
+
+    "fmt"
+    "os"
+    "path/filepath"
+)
+
+// Load icons from `dir` that replace the built-in Named icons. Each file is
+// named after the icon it replaces, like "Swap.png" or "SplitTop.png".
+// Icons without a file keep the built-in image, so a missing directory just
+// means no replacements.
+func LoadIconDir(dir string) (map[string]imglib.Image, error) {
+    icons := make(map[string]imglib.Image)
+    for name := range Named {
+        path := filepath.Join(dir, name + ".png")
+        file, err := os.Open(path)
+        if err != nil {
+            if os.IsNotExist(err) { continue }
+            return nil, err
+        }
+
+        img, _, err := imglib.Decode(file)
+        file.Close()
+        if err != nil {
+            return nil, fmt.Errorf("icon %s: %v", path, err)
+        }
+        logger.Printf("using %s for the %s icon\n", path, name)
+        icons[name] = img
+    }
+    return icons, nil
+}
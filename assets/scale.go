@@ -0,0 +1,158 @@
+package assets
+
+/*
+    Resample icons to any size, so the UI doesn't shrink to nothing on
+    high-DPI screens. Icons are drawn at 96 dpi and scaled from there.
+*/
+
+import (
+    imglib "image"
+    "image/color"
+    "math"
+)
+
+// the size icons are drawn at, before scaling. All of the icons are the
+// same size, but they aren't square.
+func IconSize() (width, height int) {
+    bounds := Swap.Bounds()
+    return bounds.Dx(), bounds.Dy()
+}
+
+// the size of an icon once scaled by `scale`
+func ScaledIconSize(scale float64) (width, height int) {
+    width, height = IconSize()
+    return scaleLength(width, scale), scaleLength(height, scale)
+}
+
+// All of the Named icons, resampled to `scale` times their drawn size
+func ScaledNamed(scale float64) map[string]imglib.Image {
+    width, height := ScaledIconSize(scale)
+    return ResampleAll(Named, width, height)
+}
+
+// every image in `images`, resampled to `width` x `height`
+func ResampleAll(images map[string]imglib.Image, width, height int) map[string]imglib.Image {
+    scaled := make(map[string]imglib.Image, len(images))
+    for name, img := range images {
+        scaled[name] = Resample(img, width, height)
+    }
+    return scaled
+}
+
+func scaleLength(length int, scale float64) int {
+    scaled := int(math.Floor(float64(length) * scale + 0.5))
+    if scaled < 1 {
+        return 1
+    }
+    return scaled
+}
+
+// Resize `src` to `width` x `height` with a Catmull-Rom filter. Filtering
+// happens on premultiplied colors, so transparent pixels don't bleed dark
+// fringes into the edges of the icon.
+func Resample(src imglib.Image, width, height int) *imglib.RGBA {
+    bounds := src.Bounds()
+    xweights := filterWeights(bounds.Dx(), width)
+    yweights := filterWeights(bounds.Dy(), height)
+
+    // horizontal pass: every source row, stretched to the new width
+    tmp := make([][4]float64, width * bounds.Dy())
+    for y := 0; y < bounds.Dy(); y++ {
+        for x, taps := range xweights {
+            var sum [4]float64
+            for _, tap := range taps {
+                r, g, b, a := src.At(bounds.Min.X + tap.index, bounds.Min.Y + y).RGBA()
+                sum[0] += float64(r) * tap.weight
+                sum[1] += float64(g) * tap.weight
+                sum[2] += float64(b) * tap.weight
+                sum[3] += float64(a) * tap.weight
+            }
+            tmp[y * width + x] = sum
+        }
+    }
+
+    // vertical pass, from the stretched rows into the destination
+    dst := imglib.NewRGBA(imglib.Rect(0, 0, width, height))
+    for y, taps := range yweights {
+        for x := 0; x < width; x++ {
+            var sum [4]float64
+            for _, tap := range taps {
+                px := tmp[tap.index * width + x]
+                for c := range sum {
+                    sum[c] += px[c] * tap.weight
+                }
+            }
+            // Catmull-Rom overshoots; keep colors inside the alpha
+            a := clamp16(sum[3])
+            dst.SetRGBA(x, y, color.RGBA{
+                to8(math.Min(clamp16(sum[0]), a)),
+                to8(math.Min(clamp16(sum[1]), a)),
+                to8(math.Min(clamp16(sum[2]), a)),
+                to8(a),
+            })
+        }
+    }
+    return dst
+}
+
+// one source pixel's contribution to a destination pixel
+type filterTap struct {
+    index  int
+    weight float64
+}
+
+// the taps for each of `dst_len` destination pixels, resampling from
+// `src_len` source pixels
+func filterWeights(src_len, dst_len int) [][]filterTap {
+    ratio := float64(src_len) / float64(dst_len)
+    // when shrinking, widen the filter so every source pixel counts
+    spread := math.Max(ratio, 1)
+    support := 2 * spread
+
+    weights := make([][]filterTap, dst_len)
+    for i := range weights {
+        // the center of destination pixel i, in source coordinates
+        center := (float64(i) + 0.5) * ratio - 0.5
+        lo := int(math.Ceil(center - support))
+        hi := int(math.Floor(center + support))
+
+        taps := []filterTap{}
+        total := 0.0
+        for j := lo; j <= hi; j++ {
+            w := catmullRom((float64(j) - center) / spread)
+            if w == 0 { continue }
+            // repeat the edge pixels past the border
+            index := j
+            if index < 0 { index = 0 }
+            if index >= src_len { index = src_len - 1 }
+            taps = append(taps, filterTap{index, w})
+            total += w
+        }
+        // normalize, so flat areas stay flat
+        for k := range taps {
+            taps[k].weight /= total
+        }
+        weights[i] = taps
+    }
+    return weights
+}
+
+func catmullRom(x float64) float64 {
+    x = math.Abs(x)
+    switch {
+    case x < 1:
+        return (1.5 * x - 2.5) * x * x + 1
+    case x < 2:
+        return ((-0.5 * x + 2.5) * x - 4) * x + 2
+    }
+    return 0
+}
+
+func clamp16(v float64) float64 {
+    return math.Max(0, math.Min(v, 0xffff))
+}
+
+// a 16-bit color channel, rounded to 8 bits
+func to8(v float64) uint8 {
+    return uint8(math.Floor(v / 0x101 + 0.5))
+}
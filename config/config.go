@@ -0,0 +1,445 @@
+/*
+Load j3's settings from a configuration file at runtime, instead of
+baking them into the binary as constants.
+
+The configuration file lives at $XDG_CONFIG_HOME/j3/config.toml, or
+~/.config/j3/config.toml if XDG_CONFIG_HOME is unset. Every setting is
+optional: anything left out of the file keeps its default value.
+
+    # example config.toml
+    key_combo_move      = "Mod4-1"
+    key_combo_resize    = "Mod4-3"
+    key_mods_swap       = "Mod4-Shift"
+    key_mods_split      = "Mod4-Control"
+    key_mods_shove      = "" # no keyboard shoves
+    direction_keys      = ["k", "l", "j", "h"]
+    adjacency_epsilon   = 10
+    dynamic_drag_resize = true
+    background_color    = "#1d1f21"
+    hover_color         = "#373b41"
+    disabled_opacity    = 0.5
+    border_color        = "#c5c8c6"
+    border_width        = 2
+    corner_radius       = 8
+    icon_dir            = "icons" # relative to the config directory
+    icon_margin         = 12
+    icon_padding        = 20
+    icon_scale          = 2.0 # or 0 to follow Xft.dpi
+    move_resize_timeout = 50 # milliseconds
+*/
+package config
+
+import (
+    "github.com/BurntSushi/toml"
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/keybind"
+
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// DEFAULTS ///////////////////////////////////////////////////////////////////
+// these are the values j3 uses when the config file doesn't say otherwise
+
+const (
+    // Opt-Shift-LeftMouseButton drags activate j3!
+    // KeyComboMove is the binding used for j3's window movement functions
+    // it is in the format (MOD_NAME-)+[1-5]
+    // where MOD_NAME is any X11 modifier name, and 1-5 is the mouse button number
+    DefaultKeyComboMove = "Mod1-Shift-1"
+
+    // key combination to activate j3's resizing mode
+    DefaultKeyComboResize = "Mod1-Control-1"
+
+    // keyboard versions of the cross's actions. Each is a set of modifiers
+    // (like the ones in KeyComboMove, but without the button) that, held
+    // with one of the DirectionKeys, acts on the focused window and its
+    // neighbor in that direction. An empty string turns the action off.
+    //  swap: trade places with the neighbor
+    DefaultKeyModsSwap = "Mod4-Shift"
+    //  split: split the neighbor, taking the half closest to us
+    DefaultKeyModsSplit = "Mod4-Control"
+    //  shove: move to the far side of the neighbor
+    DefaultKeyModsShove = "Mod4-Mod1"
+
+    // how far apart the edges of two windows can be before they are no longer
+    // considered adjacent edges
+    DefaultAdjacencyEpsilon = 6
+
+    // if true, j3 will resize windows as the mouse cursor moves. Resizes are
+    // queued per window, so slow window managers like Fluxbox just see fewer,
+    // bigger steps instead of a pile-up of requests
+    DefaultDynamicDragResize = false
+
+    // Look-and-feel options
+    DefaultBackgroundColor = 0x262626 // in hexadecimal #ff00ff style
+    DefaultHoverColor = 0x4e4e4e // behind the icon under the mouse
+    DefaultDisabledOpacity = 0.35 // of icons that have no action
+    DefaultBorderColor = 0x000000
+    DefaultBorderWidth = 0
+    DefaultCornerRadius = 0
+    // icons in the icon directory named after an action, like Swap.png,
+    // replace the built-in ones
+    DefaultIconDir = "icons" // relative to the config directory
+    DefaultIconMargin = 15 // space between icons and border
+    DefaultIconPadding = 25 // space between two icons
+    // how much to enlarge the icons, margin and padding, for high-DPI
+    // screens. 0 means work it out from the Xft.dpi X resource
+    DefaultIconScale = 0.0
+
+    // how long to wait for the window manager to move or resize a window
+    // before giving up on it
+    DefaultMoveResizeTimeout = time.Millisecond * 30
+)
+
+// the keys for each direction, in the order Top, Right, Bottom, Left.
+// Any key name xgbutil's keybind understands works, eg "k" or "Up"
+var DefaultDirectionKeys = []string{"Up", "Right", "Down", "Left"}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// name of the config file inside the j3 config directory
+const FileName = "config.toml"
+
+// modifier names understood by xgbutil's mousebind and keybind ParseString
+var modifierNames = map[string]bool{
+    "shift": true, "lock": true, "control": true,
+    "mod1": true, "mod2": true, "mod3": true, "mod4": true, "mod5": true,
+    "any": true,
+}
+
+// All of j3's runtime-tweakable settings
+type Config struct {
+    KeyComboMove        string
+    KeyComboResize      string
+    KeyModsSwap         string
+    KeyModsSplit        string
+    KeyModsShove        string
+    DirectionKeys       []string
+    AdjacencyEpsilon    int
+    DynamicDragResize   bool
+    BackgroundColor     uint32
+    HoverColor          uint32
+    DisabledOpacity     float64
+    BorderColor         uint32
+    BorderWidth         int
+    CornerRadius        int
+    IconDir             string
+    IconMargin          int
+    IconPadding         int
+    IconScale           float64
+    MoveResizeTimeout   time.Duration
+}
+
+// the config file as it appears on disk. Pointers let us tell "not set"
+// apart from a zero value, so unset options keep their defaults.
+type fileConfig struct {
+    KeyComboMove        *string `toml:"key_combo_move"`
+    KeyComboResize      *string `toml:"key_combo_resize"`
+    KeyModsSwap         *string `toml:"key_mods_swap"`
+    KeyModsSplit        *string `toml:"key_mods_split"`
+    KeyModsShove        *string `toml:"key_mods_shove"`
+    DirectionKeys       []string `toml:"direction_keys"`
+    AdjacencyEpsilon    *int    `toml:"adjacency_epsilon"`
+    DynamicDragResize   *bool   `toml:"dynamic_drag_resize"`
+    BackgroundColor     *string `toml:"background_color"`
+    HoverColor          *string `toml:"hover_color"`
+    DisabledOpacity     *float64 `toml:"disabled_opacity"`
+    BorderColor         *string `toml:"border_color"`
+    BorderWidth         *int    `toml:"border_width"`
+    CornerRadius        *int    `toml:"corner_radius"`
+    IconDir             *string `toml:"icon_dir"`
+    IconMargin          *int    `toml:"icon_margin"`
+    IconPadding         *int    `toml:"icon_padding"`
+    IconScale           *float64 `toml:"icon_scale"`
+    MoveResizeTimeout   *int    `toml:"move_resize_timeout"` // milliseconds
+}
+
+// the problems with a config file, all at once, so the user can fix them
+// in one go instead of one restart per typo
+type Error struct {
+    Path     string
+    Problems []string
+}
+
+func (err *Error) Error() string {
+    return fmt.Sprintf("config %s: %s", err.Path, strings.Join(err.Problems, "; "))
+}
+
+// a config built entirely from the default values
+func Default() *Config {
+    return &Config{
+        KeyComboMove:       DefaultKeyComboMove,
+        KeyComboResize:     DefaultKeyComboResize,
+        KeyModsSwap:        DefaultKeyModsSwap,
+        KeyModsSplit:       DefaultKeyModsSplit,
+        KeyModsShove:       DefaultKeyModsShove,
+        DirectionKeys:      append([]string{}, DefaultDirectionKeys...),
+        AdjacencyEpsilon:   DefaultAdjacencyEpsilon,
+        DynamicDragResize:  DefaultDynamicDragResize,
+        BackgroundColor:    DefaultBackgroundColor,
+        HoverColor:         DefaultHoverColor,
+        DisabledOpacity:    DefaultDisabledOpacity,
+        BorderColor:        DefaultBorderColor,
+        BorderWidth:        DefaultBorderWidth,
+        CornerRadius:       DefaultCornerRadius,
+        IconDir:            filepath.Join(Dir(), DefaultIconDir),
+        IconMargin:         DefaultIconMargin,
+        IconPadding:        DefaultIconPadding,
+        IconScale:          DefaultIconScale,
+        MoveResizeTimeout:  DefaultMoveResizeTimeout,
+    }
+}
+
+// the directory j3 keeps its configuration in
+func Dir() string {
+    base := os.Getenv("XDG_CONFIG_HOME")
+    if base == "" {
+        base = filepath.Join(os.Getenv("HOME"), ".config")
+    }
+    return filepath.Join(base, "j3")
+}
+
+// full path of the config file
+func Path() string {
+    return filepath.Join(Dir(), FileName)
+}
+
+// Load the config file from the default location. A missing config file is
+// not an error: you just get the defaults.
+func Load(X *xgbutil.XUtil) (*Config, error) {
+    return LoadFile(X, Path())
+}
+
+// Load a config file from `path`, filling in defaults for any settings it
+// doesn't mention. A missing file yields the defaults; a file with bad values
+// yields a *config.Error listing every bad value.
+//
+// Key names are looked up on X's keyboard, so keybind.Initialize must have
+// been called on it. With a nil X they're only checked for being there.
+func LoadFile(X *xgbutil.XUtil, path string) (*Config, error) {
+    conf := Default()
+
+    var raw fileConfig
+    meta, err := toml.DecodeFile(path, &raw)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return conf, nil
+        }
+        return nil, fmt.Errorf("config %s: %v", path, err)
+    }
+
+    problems := []string{}
+    for _, key := range meta.Undecoded() {
+        problems = append(problems, fmt.Sprintf("unknown setting %q", key.String()))
+    }
+
+    if raw.KeyComboMove != nil {
+        if err := ValidateKeyCombo(*raw.KeyComboMove); err != nil {
+            problems = append(problems, fmt.Sprintf("key_combo_move: %v", err))
+        }
+        conf.KeyComboMove = *raw.KeyComboMove
+    }
+    if raw.KeyComboResize != nil {
+        if err := ValidateKeyCombo(*raw.KeyComboResize); err != nil {
+            problems = append(problems, fmt.Sprintf("key_combo_resize: %v", err))
+        }
+        conf.KeyComboResize = *raw.KeyComboResize
+    }
+    if raw.KeyModsSwap != nil {
+        if err := ValidateModifiers(*raw.KeyModsSwap); err != nil {
+            problems = append(problems, fmt.Sprintf("key_mods_swap: %v", err))
+        }
+        conf.KeyModsSwap = *raw.KeyModsSwap
+    }
+    if raw.KeyModsSplit != nil {
+        if err := ValidateModifiers(*raw.KeyModsSplit); err != nil {
+            problems = append(problems, fmt.Sprintf("key_mods_split: %v", err))
+        }
+        conf.KeyModsSplit = *raw.KeyModsSplit
+    }
+    if raw.KeyModsShove != nil {
+        if err := ValidateModifiers(*raw.KeyModsShove); err != nil {
+            problems = append(problems, fmt.Sprintf("key_mods_shove: %v", err))
+        }
+        conf.KeyModsShove = *raw.KeyModsShove
+    }
+    if raw.DirectionKeys != nil {
+        if len(raw.DirectionKeys) != 4 {
+            problems = append(problems, fmt.Sprintf("direction_keys: want 4 keys, for Top, Right, Bottom and Left (was %d)", len(raw.DirectionKeys)))
+        }
+        for _, key := range raw.DirectionKeys {
+            if err := ValidateKeyName(X, key); err != nil {
+                problems = append(problems, fmt.Sprintf("direction_keys: %v", err))
+            }
+        }
+        conf.DirectionKeys = raw.DirectionKeys
+    }
+    if raw.AdjacencyEpsilon != nil {
+        if *raw.AdjacencyEpsilon < 0 {
+            problems = append(problems, fmt.Sprintf("adjacency_epsilon: must not be negative (was %d)", *raw.AdjacencyEpsilon))
+        }
+        conf.AdjacencyEpsilon = *raw.AdjacencyEpsilon
+    }
+    if raw.DynamicDragResize != nil {
+        conf.DynamicDragResize = *raw.DynamicDragResize
+    }
+    if raw.BackgroundColor != nil {
+        clr, err := ParseColor(*raw.BackgroundColor)
+        if err != nil {
+            problems = append(problems, fmt.Sprintf("background_color: %v", err))
+        }
+        conf.BackgroundColor = clr
+    }
+    if raw.HoverColor != nil {
+        clr, err := ParseColor(*raw.HoverColor)
+        if err != nil {
+            problems = append(problems, fmt.Sprintf("hover_color: %v", err))
+        }
+        conf.HoverColor = clr
+    }
+    if raw.DisabledOpacity != nil {
+        if *raw.DisabledOpacity < 0 || *raw.DisabledOpacity > 1 {
+            problems = append(problems, fmt.Sprintf("disabled_opacity: must be between 0 and 1 (was %v)", *raw.DisabledOpacity))
+        }
+        conf.DisabledOpacity = *raw.DisabledOpacity
+    }
+    if raw.BorderColor != nil {
+        clr, err := ParseColor(*raw.BorderColor)
+        if err != nil {
+            problems = append(problems, fmt.Sprintf("border_color: %v", err))
+        }
+        conf.BorderColor = clr
+    }
+    if raw.BorderWidth != nil {
+        if *raw.BorderWidth < 0 {
+            problems = append(problems, fmt.Sprintf("border_width: must not be negative (was %d)", *raw.BorderWidth))
+        }
+        conf.BorderWidth = *raw.BorderWidth
+    }
+    if raw.CornerRadius != nil {
+        if *raw.CornerRadius < 0 {
+            problems = append(problems, fmt.Sprintf("corner_radius: must not be negative (was %d)", *raw.CornerRadius))
+        }
+        conf.CornerRadius = *raw.CornerRadius
+    }
+    if raw.IconDir != nil {
+        // relative paths are relative to the config file
+        dir := *raw.IconDir
+        if !filepath.IsAbs(dir) {
+            dir = filepath.Join(filepath.Dir(path), dir)
+        }
+        conf.IconDir = dir
+    }
+    if raw.IconMargin != nil {
+        if *raw.IconMargin < 0 {
+            problems = append(problems, fmt.Sprintf("icon_margin: must not be negative (was %d)", *raw.IconMargin))
+        }
+        conf.IconMargin = *raw.IconMargin
+    }
+    if raw.IconPadding != nil {
+        if *raw.IconPadding < 0 {
+            problems = append(problems, fmt.Sprintf("icon_padding: must not be negative (was %d)", *raw.IconPadding))
+        }
+        conf.IconPadding = *raw.IconPadding
+    }
+    if raw.IconScale != nil {
+        if *raw.IconScale < 0 {
+            problems = append(problems, fmt.Sprintf("icon_scale: must not be negative (was %v)", *raw.IconScale))
+        }
+        conf.IconScale = *raw.IconScale
+    }
+    if raw.MoveResizeTimeout != nil {
+        if *raw.MoveResizeTimeout <= 0 {
+            problems = append(problems, fmt.Sprintf("move_resize_timeout: must be a positive number of milliseconds (was %d)", *raw.MoveResizeTimeout))
+        }
+        conf.MoveResizeTimeout = time.Duration(*raw.MoveResizeTimeout) * time.Millisecond
+    }
+
+    if len(problems) > 0 {
+        return nil, &Error{path, problems}
+    }
+    return conf, nil
+}
+
+// Check that a key combo looks like something mousebind can bind:
+// zero or more modifier names followed by a mouse button number 1-5,
+// all joined by dashes, eg "Mod1-Shift-1"
+func ValidateKeyCombo(combo string) error {
+    if combo == "" {
+        return fmt.Errorf("key combo is empty")
+    }
+
+    parts := strings.Split(combo, "-")
+    button := parts[len(parts)-1]
+    if err := checkModifiers(combo, parts[:len(parts)-1]); err != nil {
+        return err
+    }
+
+    n, err := strconv.Atoi(button)
+    if err != nil || n < 1 || n > 5 {
+        return fmt.Errorf("%q: must end in a mouse button number 1-5, not %q", combo, button)
+    }
+    return nil
+}
+
+// Check that `key` is a single key that keybind can find on X's keyboard,
+// eg "k" or "Up". Modifiers are configured separately, so a key name with
+// dashes in it is a mistake. With a nil X, any other non-empty name passes.
+func ValidateKeyName(X *xgbutil.XUtil, key string) error {
+    if key == "" {
+        return fmt.Errorf("key names must not be empty")
+    }
+    if strings.Contains(key, "-") {
+        return fmt.Errorf("%q: just the key, please: modifiers go in key_mods_swap, key_mods_split and key_mods_shove", key)
+    }
+    if X == nil { return nil }
+
+    // this also catches modifier names like "Shift", which have no key in them
+    _, _, err := keybind.ParseString(X, key)
+    if err != nil {
+        return fmt.Errorf("%q: no such key on this keyboard", key)
+    }
+    return nil
+}
+
+// Check that a set of modifiers looks like something keybind can bind:
+// zero or more modifier names joined by dashes, eg "Mod4-Shift". Empty is
+// fine, and means the binding is turned off.
+func ValidateModifiers(mods string) error {
+    if mods == "" { return nil }
+    return checkModifiers(mods, strings.Split(mods, "-"))
+}
+
+func checkModifiers(combo string, mods []string) error {
+    for _, mod := range mods {
+        if !modifierNames[strings.ToLower(mod)] {
+            return fmt.Errorf("%q: unknown modifier %q (want one of Shift, Lock, Control, Mod1-Mod5, Any)", combo, mod)
+        }
+    }
+    return nil
+}
+
+// Parse an RGB color in "#ff00ff" or "0xff00ff" style
+func ParseColor(s string) (uint32, error) {
+    hex := s
+    switch {
+    case strings.HasPrefix(hex, "#"):
+        hex = hex[1:]
+    case strings.HasPrefix(hex, "0x"), strings.HasPrefix(hex, "0X"):
+        hex = hex[2:]
+    }
+
+    if len(hex) != 6 {
+        return 0, fmt.Errorf("%q: colors must have six hex digits, like \"#262626\"", s)
+    }
+    clr, err := strconv.ParseUint(hex, 16, 32)
+    if err != nil {
+        return 0, fmt.Errorf("%q: not a hex color: %v", s, err)
+    }
+    return uint32(clr), nil
+}
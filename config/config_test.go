@@ -0,0 +1,245 @@
+package config
+
+import (
+    "io/ioutil"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+// write `contents` to a config file in a fresh directory, and return its path
+func writeConfig(t *testing.T, contents string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), FileName)
+    err := ioutil.WriteFile(path, []byte(contents), 0644)
+    if err != nil { t.Fatal(err) }
+    return path
+}
+
+func TestLoadMissingFile(t *testing.T) {
+    conf, err := LoadFile(nil, filepath.Join(t.TempDir(), "nope.toml"))
+    if err != nil { t.Fatal(err) }
+    if !reflect.DeepEqual(conf, Default()) {
+        t.Errorf("got %+v, want the defaults %+v", conf, Default())
+    }
+}
+
+// settings the file leaves out keep their defaults
+func TestLoadDefaults(t *testing.T) {
+    tests := []struct {
+        name        string
+        contents    string
+        change      func(conf *Config, dir string)
+    }{
+        {"empty", "", func(*Config, string) {}},
+        {"one combo", `key_combo_move = "Mod4-1"`, func(conf *Config, dir string) {
+            conf.KeyComboMove = "Mod4-1"
+        }},
+        {"keys turned off", `key_mods_shove = ""`, func(conf *Config, dir string) {
+            conf.KeyModsShove = ""
+        }},
+        {"direction keys", `direction_keys = ["k", "l", "j", "h"]`, func(conf *Config, dir string) {
+            conf.DirectionKeys = []string{"k", "l", "j", "h"}
+        }},
+        {"colors", "background_color = \"#1d1f21\"\nhover_color = \"0x373B41\"", func(conf *Config, dir string) {
+            conf.BackgroundColor = 0x1d1f21
+            conf.HoverColor = 0x373b41
+        }},
+        {"timeout", `move_resize_timeout = 50`, func(conf *Config, dir string) {
+            conf.MoveResizeTimeout = 50 * time.Millisecond
+        }},
+        // relative to the config file, not the working directory
+        {"icon dir", `icon_dir = "pics"`, func(conf *Config, dir string) {
+            conf.IconDir = filepath.Join(dir, "pics")
+        }},
+        {"absolute icon dir", `icon_dir = "/usr/share/j3"`, func(conf *Config, dir string) {
+            conf.IconDir = "/usr/share/j3"
+        }},
+    }
+
+    for _, test := range tests {
+        path := writeConfig(t, test.contents)
+        got, err := LoadFile(nil, path)
+        if err != nil {
+            t.Errorf("%s: %v", test.name, err)
+            continue
+        }
+        want := Default()
+        test.change(want, filepath.Dir(path))
+        if !reflect.DeepEqual(got, want) {
+            t.Errorf("%s: got %+v, want %+v", test.name, got, want)
+        }
+    }
+}
+
+func TestParseColor(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    uint32
+        ok      bool
+    }{
+        {"#ff00ff", 0xff00ff, true},
+        {"0x262626", 0x262626, true},
+        {"0XABCDEF", 0xabcdef, true},
+        {"262626", 0x262626, true},
+        {"", 0, false},
+        {"#fff", 0, false},
+        {"#ff00ff00", 0, false},
+        {"#gg0000", 0, false},
+        {"red", 0, false},
+    }
+
+    for _, test := range tests {
+        got, err := ParseColor(test.in)
+        if (err == nil) != test.ok {
+            t.Errorf("ParseColor(%q): got error %v, want ok == %v", test.in, err, test.ok)
+            continue
+        }
+        if test.ok && got != test.want {
+            t.Errorf("ParseColor(%q) = %#06x, want %#06x", test.in, got, test.want)
+        }
+    }
+}
+
+func TestValidateKeyCombo(t *testing.T) {
+    tests := []struct {
+        combo   string
+        ok      bool
+    }{
+        {"1", true},
+        {"Mod1-Shift-1", true},
+        {"mod4-control-5", true},
+        {"Any-3", true},
+        {"", false},
+        {"Mod1-Shift", false},
+        {"Mod1-6", false},
+        {"Mod1-0", false},
+        {"Hyper-1", false},
+        {"Mod1--1", false},
+        {"Mod1-a", false},
+    }
+
+    for _, test := range tests {
+        err := ValidateKeyCombo(test.combo)
+        if (err == nil) != test.ok {
+            t.Errorf("ValidateKeyCombo(%q): got error %v, want ok == %v", test.combo, err, test.ok)
+        }
+    }
+}
+
+func TestValidateModifiers(t *testing.T) {
+    tests := []struct {
+        mods    string
+        ok      bool
+    }{
+        {"", true},
+        {"Mod4", true},
+        {"Mod4-Shift", true},
+        {"lock-mod2", true},
+        {"Mod4-1", false},
+        {"Mod4-", false},
+        {"Super", false},
+    }
+
+    for _, test := range tests {
+        err := ValidateModifiers(test.mods)
+        if (err == nil) != test.ok {
+            t.Errorf("ValidateModifiers(%q): got error %v, want ok == %v", test.mods, err, test.ok)
+        }
+    }
+}
+
+// without X, key names are only checked for their shape
+func TestValidateKeyName(t *testing.T) {
+    tests := []struct {
+        key     string
+        ok      bool
+    }{
+        {"k", true},
+        {"Up", true},
+        {"minus", true},
+        {"", false},
+        {"Shift-k", false},
+        {"-", false},
+    }
+
+    for _, test := range tests {
+        err := ValidateKeyName(nil, test.key)
+        if (err == nil) != test.ok {
+            t.Errorf("ValidateKeyName(%q): got error %v, want ok == %v", test.key, err, test.ok)
+        }
+    }
+}
+
+// every problem in the file is reported at once, each under its setting
+func TestLoadErrors(t *testing.T) {
+    tests := []struct {
+        name        string
+        contents    string
+        // the setting each problem should be about, in order
+        want        []string
+    }{
+        {"bad color", `hover_color = "blue"`, []string{"hover_color"}},
+        {"bad combo", `key_combo_resize = "Mod1-Control"`, []string{"key_combo_resize"}},
+        {"unknown setting", `key_combo_mvoe = "1"`, []string{`unknown setting "key_combo_mvoe"`}},
+        {"direction keys", `direction_keys = ["k", ""]`,
+            []string{"direction_keys", "direction_keys"}},
+        {"several", strings.Join([]string{
+            `key_combo_move = "Mod1-9"`,
+            `key_mods_swap = "Mod4-1"`,
+            `adjacency_epsilon = -1`,
+            `background_color = "#12345"`,
+            `disabled_opacity = 1.5`,
+            `border_width = -2`,
+            `move_resize_timeout = 0`,
+        }, "\n"), []string{
+            "key_combo_move",
+            "key_mods_swap",
+            "adjacency_epsilon",
+            "background_color",
+            "disabled_opacity",
+            "border_width",
+            "move_resize_timeout",
+        }},
+    }
+
+    for _, test := range tests {
+        path := writeConfig(t, test.contents)
+        conf, err := LoadFile(nil, path)
+        if err == nil {
+            t.Errorf("%s: got %+v, want an error", test.name, conf)
+            continue
+        }
+        conf_err, ok := err.(*Error)
+        if !ok {
+            t.Errorf("%s: got %T %v, want a *config.Error", test.name, err, err)
+            continue
+        }
+        if conf_err.Path != path {
+            t.Errorf("%s: error is for %s, want %s", test.name, conf_err.Path, path)
+        }
+        if len(conf_err.Problems) != len(test.want) {
+            t.Errorf("%s: got problems %q, want one each for %q", test.name, conf_err.Problems, test.want)
+            continue
+        }
+        for i, problem := range conf_err.Problems {
+            if !strings.HasPrefix(problem, test.want[i]) {
+                t.Errorf("%s: problem %d is %q, want it to be about %s", test.name, i, problem, test.want[i])
+            }
+        }
+    }
+}
+
+// a file that isn't TOML at all is an error, but not a list of problems
+func TestLoadSyntaxError(t *testing.T) {
+    path := writeConfig(t, `key_combo_move = `)
+    _, err := LoadFile(nil, path)
+    if err == nil {
+        t.Fatal("got no error")
+    }
+    if _, ok := err.(*Error); ok {
+        t.Errorf("got a *config.Error %v, want a syntax error", err)
+    }
+}
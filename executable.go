@@ -11,11 +11,14 @@ import (
     "github.com/BurntSushi/xgbutil/ewmh"
     "github.com/BurntSushi/xgbutil/xwindow"
     "github.com/BurntSushi/xgbutil/mousebind"
+    "github.com/BurntSushi/xgbutil/keybind"
 
+    "fmt"
     logLib "log"
     "os"
 
     "github.com/justjake/j3/assets"
+    "github.com/justjake/j3/config"
     "github.com/justjake/j3/ui"
     "github.com/justjake/j3/wm"
     "github.com/justjake/j3/util"
@@ -24,62 +27,131 @@ import (
 
 
 // CONFIGURATION //////////////////////////////////////////////////////////////
-// feel free to change the values here to customize j3
-
-const (
-    // Opt-Shift-LeftMouseButton drags activate j3!
-    // MoveKeyCombo is the binding used for j3's window movement functions
-    // it is in the format (MOD_NAME-)+[1-5]
-    // where MOD_NAME is any X11 keyname, and 1-5 is the mouse button number
-    KeyComboMove = ui.KeyOption+"-Shift-1"
-
-    // key combination to activate j3's resizing mode
-    KeyComboResize = ui.KeyOption+"-Control-1"
-
-    // how far apart the edges of two windows can be before they are no longer
-    // considered adjacent edges
-    AdjacencyEpsilon = 6
-
-    // if true, j3 will try to resize windows as the mouse cursor moves. This works 
-    // well under some window managers (like Openbox), but performs horribly
-    // under others, like Fluxbox
-    //
-    // TODO: implement per-window resize locks to prevent the race conditions
-    // that make dynamic updates unfeasable in Fluxbox
-    DynamicDragResize = false
-
-    // Look-and-feel options
-    BackgroundColor = 0x262626  // in hexadecimal #ff00ff style
-    IconMargin = 15 // space between icons and border
-    IconPadding = 25 // space between two icons
-)
+// j3 reads its settings from $XDG_CONFIG_HOME/j3/config.toml at startup.
+// See the config package for the available settings and their defaults.
 
 ///////////////////////////////////////////////////////////////////////////////
 
 
 var (
-    // TODO: icons are not square. Make the geometry calculations right!
-    // TODO: IconWidth and IconHeight replace IconSize
-    IconSize = assets.Swap.Bounds().Dx() // assume square icons
-
     log = logLib.New(os.Stderr, "[j3] ", logLib.LstdFlags | logLib.Lshortfile)
 )
 
-func makeCross(X *xgbutil.XUtil) *ui.Cross {
+// how thick the preview outlines are, at 96 dpi
+const GhostThickness = 4
+
+// the layouts the cross can take, biggest first. Small target windows get
+// the compact cross, which drops the shoves, or just the swap icon.
+var crossLayouts = []*ui.CrossLayout{
+    {
+        Name:       "full",
+        Vertical:   []string{"ShoveTop", "SplitTop", "Swap", "SplitBottom", "ShoveBottom"},
+        Horizontal: []string{"ShoveLeft", "SplitLeft", "Swap", "SplitRight", "ShoveRight"},
+    },
+    {
+        Name:       "compact",
+        Vertical:   []string{"SplitTop", "Swap", "SplitBottom"},
+        Horizontal: []string{"SplitLeft", "Swap", "SplitRight"},
+    },
+    {
+        Name:       "swap",
+        Vertical:   []string{"Swap"},
+        Horizontal: []string{"Swap"},
+    },
+}
+
+// build the cross's theme from the config. Sizes in the config are at 96
+// dpi, so they're multiplied by `scale`, and replacement icons are resampled
+// to `icon_width` x `icon_height`.
+func makeTheme(conf *config.Config, scale float64, icon_width, icon_height int) (*ui.Theme, error) {
+    icons, err := assets.LoadIconDir(conf.IconDir)
+    if err != nil { return nil, err }
+
+    return &ui.Theme{
+        Background:         ui.RGB(conf.BackgroundColor),
+        Hover:              ui.RGB(conf.HoverColor),
+        DisabledOpacity:    conf.DisabledOpacity,
+        Border:             ui.RGB(conf.BorderColor),
+        BorderWidth:        scaleInt(conf.BorderWidth, scale),
+        CornerRadius:       scaleInt(conf.CornerRadius, scale),
+        Icons:              assets.ResampleAll(icons, icon_width, icon_height),
+    }, nil
+}
+
+func scaleInt(n int, scale float64) int {
+    return int(float64(n) * scale + 0.5)
+}
+
+// how much to scale everything up on high-DPI screens
+func uiScale(X *xgbutil.XUtil, conf *config.Config) float64 {
+    if conf.IconScale == 0 {
+        return ui.DPIScale(X)
+    }
+    return conf.IconScale
+}
+
+func makeCross(X *xgbutil.XUtil, conf *config.Config) (*ui.Cross, error) {
+    scale := uiScale(X, conf)
+    icon_width, icon_height := assets.ScaledIconSize(scale)
+    margin := scaleInt(conf.IconMargin, scale)
+    padding := scaleInt(conf.IconPadding, scale)
+    log.Printf("Drawing %dx%d icons at %.2fx scale\n", icon_width, icon_height, scale)
+
+    theme, err := makeTheme(conf, scale, icon_width, icon_height)
+    if err != nil { return nil, err }
+
     // create a basic cross. We will have to initalize the window later.
-    cross_ui := ui.NewCross(assets.Named, IconSize, IconMargin, IconPadding)
-    vert_icons :=  []string{"ShoveTop", "SplitTop", "Swap", "SplitBottom", "ShoveBottom"}
-    horiz_icons := []string{"ShoveLeft", "SplitLeft", "Swap", "SplitRight", "ShoveRight"}
+    cross_ui := ui.NewCross(assets.ScaledNamed(scale), theme, icon_width, icon_height, margin, padding)
 
-    _, err := cross_ui.CreateWindow(X, len(vert_icons), BackgroundColor)
-    util.Fatal(err)
+    // creating the window also positions the icons for the first layout
+    _, err = cross_ui.CreateWindow(X, crossLayouts)
+    if err != nil { return nil, err }
 
-    // position icons on the cross
-    offset := IconMargin + 2 * (IconSize + IconPadding)
-    cross_ui.LayoutHorizontalIcons(horiz_icons, offset)
-    cross_ui.LayoutVerticalIcons(vert_icons, offset)
+    return cross_ui, nil
+}
 
-    return cross_ui
+// map the icons on the cross the the actions they should perform
+// when objects are dropped over them
+func mapActions(cross_ui *ui.Cross) map[xproto.Window]wm.WindowInteraction {
+    win_to_action := make(map[xproto.Window]wm.WindowInteraction)
+    for name, icon := range cross_ui.Icons {
+        if action, ok := wm.Actions[name]; ok {
+            win_to_action[icon.Window.Id] = action
+        } else {
+            // otherwise,
+            // shade the icon because it has no action attatched to it
+            icon.SetState(ui.StateDisabled)
+        }
+    }
+    return win_to_action
+}
+
+// map the icons on the cross to dry runs of their actions, for previews
+func mapPlans(cross_ui *ui.Cross) map[xproto.Window]wm.Planner {
+    win_to_plan := make(map[xproto.Window]wm.Planner)
+    for name, icon := range cross_ui.Icons {
+        if plan, ok := wm.Plans[name]; ok {
+            win_to_plan[icon.Window.Id] = plan
+        }
+    }
+    return win_to_plan
+}
+
+// `n` ghost outlines, eg one for each window an action can move
+func makeGhosts(X *xgbutil.XUtil, conf *config.Config, theme *ui.Theme, n int) ([]*ui.Ghost, error) {
+    thickness := scaleInt(GhostThickness, uiScale(X, conf))
+    ghosts := make([]*ui.Ghost, n)
+    for i := range ghosts {
+        ghost, err := ui.NewGhost(X, theme, thickness)
+        if err != nil {
+            for _, made := range ghosts[:i] {
+                made.Destroy()
+            }
+            return nil, err
+        }
+        ghosts[i] = ghost
+    }
+    return ghosts, nil
 }
 
 
@@ -97,31 +169,148 @@ func main() {
     // initiate extension tools
     shape.Init(X.Conn())
     mousebind.Initialize(X)
+    keybind.Initialize(X)
+
+    // read user settings as soon as we can look up their key names, so a
+    // bad config fails fast
+    conf, err := config.Load(X)
+    fatal(err)
+    log.Printf("Using config from %s\n", config.Path())
+
+    // follow window geometry through ConfigureNotify events, instead of
+    // polling X while we wait for the window manager
+    wm.StartTracking(X)
+    // keep the monitor layout fresh for wm.UsableArea and the cross
+    fatal(wm.WatchHeads(X))
 
     // Detail our current window manager. Insures a minimum of EWMH compliance
     wm_name, err := ewmh.GetEwmhWM(X)
     fatal(err)
     log.Printf("Window manager: %s\n", wm_name)
 
-    // create the cross UI
-    cross_ui := makeCross(X)
-    cross := cross_ui.Window
+    // the cross UI and its icon -> action map. These are rebuilt by
+    // applyConfig whenever the config changes, so the drag handlers
+    // must always go through these variables
+    var cross_ui *ui.Cross
+    var win_to_action map[xproto.Window]wm.WindowInteraction
+    // previews of what each icon will do
+    var win_to_plan map[xproto.Window]wm.Planner
+    var ghosts []*ui.Ghost
+    // outlines the target when other windows are stacked with it under the
+    // pointer, so it's clear which one the cross is for
+    var outline *ui.Ghost
+    // the topmost window under the pointer at the last drag step. The
+    // scroll wheel can pick a target below it, which sticks until the
+    // pointer moves onto a different window.
+    var last_top xproto.Window
+    // the move and resize bindings
+    var move_drag, resize_drag *util.Drag
+    // follows the pointer during a move, showing what's being moved
+    var drag_icon *ui.DragIcon
+
+    // put the cross on top of the target and any outlines, keeping the
+    // drag icon on top of that
+    raiseCross := func() {
+        cross_ui.Window.Stack(xproto.StackModeAbove)
+        if drag_icon != nil {
+            drag_icon.Raise()
+        }
+    }
 
-    // map the icons on the cross the the actions they should perform 
-    // when objects are dropped over them
-    win_to_action := make(map[xproto.Window]wm.WindowInteraction)
-    for name, icon := range cross_ui.Icons {
-        if action, ok := wm.Actions[name]; ok {
-            win_to_action[icon.Window.Id] = action
-        } else {
-            // otherwise,
-            // shade the icon because it has no action attatched to it
-            icon.SetState(ui.StateDisabled)
+    // outline where the windows will go if the user drops `incoming` on
+    // `target` with `icon`
+    preview := func(target, incoming, icon xproto.Window) {
+        plan_fn, ok := win_to_plan[icon]
+        if !ok {
+            hideGhosts(ghosts)
+            return
+        }
+
+        plan, err := plan_fn(xwindow.New(X, target), xwindow.New(X, incoming))
+        if err != nil {
+            log.Printf("DragStep: can't preview this action: %v\n", err)
+            hideGhosts(ghosts)
+            return
+        }
+        showPlan(plan, ghosts)
+        // keep the icons visible over the outlines
+        raiseCross()
+    }
+
+    // hide everything a drag put over the target
+    hideDragUI := func() {
+        cross_ui.Window.Unmap()
+        cross_ui.Hover(0)
+        hideGhosts(ghosts)
+        outline.Hide()
+    }
+
+    // the drag is over: hide all of its UI, and get rid of the drag icon
+    endDragUI := func() {
+        hideDragUI()
+        if drag_icon != nil {
+            drag_icon.Destroy()
+            drag_icon = nil
+        }
+    }
+
+    // the drag-drop operation in progress. The hooks keep the UI in step
+    // with it; the drag handlers below move it from state to state.
+    session := util.NewDragSession(util.DragHooks{
+        Armed: func(s *util.DragSession, from util.DragState) {
+            last_top = 0
+            // as tall as the icons on the cross. It shows up once the
+            // pointer moves
+            drag_icon = ui.NewDragIcon(X, s.Incoming(), cross_ui.Theme, cross_ui.IconHeight)
+        },
+        OverDesktop: func(s *util.DragSession, from util.DragState) {
+            hideDragUI()
+        },
+        OverTarget: func(s *util.DragSession, from util.DragState) {
+            // any preview was for another icon or target
+            hideGhosts(ghosts)
+        },
+        OverIcon: func(s *util.DragSession, from util.DragState) {
+            preview(s.Target(), s.Incoming(), s.Icon())
+        },
+        Cancelled: func(s *util.DragSession, from util.DragState) {
+            endDragUI()
+        },
+        Committed: func(s *util.DragSession, from util.DragState) {
+            endDragUI()
+
+            // retrieve the action that this icon indicates
+            action, ok := win_to_action[s.Icon()]
+            if !ok {
+                log.Printf("DragEnd: couldn't map window %v to an action\n", s.Icon())
+                return
+            }
+            t_win, inc_win := xwindow.New(X, s.Target()), xwindow.New(X, s.Incoming())
+
+            // perform the action! in the background, so the event loop is
+            // free to deliver the geometry changes the action waits on
+            wm.Configures.Submit(t_win.Id, func() error {
+                return action(t_win, inc_win)
+            })
+        },
+    })
+
+    // light up the cross's icon `icon_win`, if it is one, so it's clear
+    // what will happen on release
+    hoverIcon := func(icon_win xproto.Window) {
+        var hovered xproto.Window
+        if icon := cross_ui.Hover(icon_win); icon != nil {
+            hovered = icon.Window.Id
+        }
+        if hovered == session.Icon() { return }
+
+        err := session.Hover(hovered)
+        if err != nil {
+            log.Printf("DragStep: %v\n", err)
         }
     }
 
     // define handlers for the three parts of any drag-drop operation
-    dm := util.DragManager{}
     handleDragStart := func(X *xgbutil.XUtil, rx, ry, ex, ey int) (cont bool, cursor xproto.Cursor) {
         // find the window we are trying to drag
         win, err := wm.FindManagedWindowUnderMouse(X)
@@ -132,104 +321,274 @@ func main() {
         }
 
         // cool awesome!
-        dm.StartDrag(win)
+        err = session.Arm(win)
+        if err != nil {
+            log.Printf("DragStart: %v\n", err)
+            return false, 0
+        }
         // continue the drag
         return true, 0
     }
 
+    // move the cross over a new target window
+    retarget := func(X *xgbutil.XUtil, win xproto.Window, rx, ry int) {
+        err := session.Enter(win)
+        if err != nil {
+            log.Printf("DragStep: %v\n", err)
+            return
+        }
+
+        // get the target width/height
+        target_geom, err := xwindow.New(X, win).Geometry()
+        if err != nil {
+            log.Printf("DragStep: issues getting target geometry: %v\n", err)
+            return
+        }
+
+        // set the target goemetry X, Y to the actual x, y relative to the root window
+        tx, ty, err := wm.TranslateCoordinatesSync(X, win, X.RootWin(), 0, 0)
+        if err != nil {
+            log.Printf("DragStep: issue translating target coordinates to root coordinates: %v\n", err)
+            return
+        }
+        target_geom.XSet(tx)
+        target_geom.YSet(ty)
+        // small or partly hidden targets may need a smaller layout
+        x, y, err := placeCross(X, cross_ui, target_geom)
+        if err != nil {
+            log.Printf("DragStep: couldn't lay out the cross: %v\n", err)
+            return
+        }
+
+        // outline the target if it's one of several under the pointer
+        outline.Hide()
+        if stacked := targetsAt(X, rx, ry, session.Incoming()); len(stacked) > 1 {
+            frame, err := wm.FrameGeometry(xwindow.New(X, win))
+            if err == nil {
+                err = outline.Show(frame)
+            }
+            if err != nil {
+                log.Printf("DragStep: couldn't outline the target: %v\n", err)
+            }
+        }
+
+        cross_ui.Window.Move(x, y)
+        cross_ui.Window.Map()
+        raiseCross()
+    }
+
     handleDragStep := func(X *xgbutil.XUtil, rx, ry, ex, ey int) {
+        if drag_icon != nil {
+            drag_icon.Follow(rx, ry)
+        }
+
+        // the cross isn't a managed window, so this has to happen before we
+        // go looking for one
+        icon_win, _, err := wm.FindNextUnderMouse(X, cross_ui.Window.Id)
+        if err == nil {
+            hoverIcon(icon_win)
+        }
+
         // see if we have a window that ISN'T the incoming window
         win, err := wm.FindManagedWindowUnderMouse(X)
         if err != nil {
-            // whatever
-            log.Printf("DragStep: no window found or something: %v\n", err)
+            // the cross isn't a managed window either, but it's only ever
+            // over the target
+            top, _, top_err := wm.FindNextUnderMouse(X, X.RootWin())
+            if top_err == nil && top == cross_ui.Window.Id { return }
+
+            // over the desktop, or a panel: nothing to drop on
+            last_top = 0
+            if session.State() != util.DragOverDesktop {
+                session.Leave()
+            }
             return
         }
 
-        // oh we have a window? and it isn't the start window!? And not the current target!?
-        if win != dm.Incoming && win != dm.Target {
-            // reposition the cross over it
-            // TODO: actually do this, center operates on rects, and all I have is this xproto.Window
-            dm.SetTarget(win)
+        // still over the same window: keep whatever target the scroll
+        // wheel picked
+        if win == last_top { return }
+        last_top = win
 
-            // get the target width/height
-            target_geom, err := xwindow.New(X, win).Geometry()
-            if err != nil {
-                log.Printf("DragStep: issues getting target geometry: %v\n", err)
-                return
+        switch {
+        case win == session.Incoming():
+            // the drag is under way, with nothing to drop on yet
+            if session.State() == util.DragArmed {
+                session.Leave()
             }
+        case win != session.Target():
+            retarget(X, win, rx, ry)
+        }
+    }
 
-            // set the target goemetry X, Y to the actual x, y relative to the root window
-            tx, ty, err := wm.TranslateCoordinatesSync(X, win, X.RootWin(), 0, 0)
-            if err != nil {
-                log.Printf("DragStep: issue translating target coordinates to root coordinates: %v\n", err)
-                return
-            }
-            target_geom.XSet(tx)
-            target_geom.YSet(ty)
-            x, y := util.CenterOver(cross.Geom, target_geom)
-            cross.Move(x, y)
-            cross.Map()
+    // the scroll wheel steps the target through every window under the
+    // pointer: down goes deeper, up comes back towards the top
+    handleDragPress := func(X *xgbutil.XUtil, button xproto.Button, rx, ry int) {
+        step := 0
+        switch button {
+        case 4: step = -1
+        case 5: step = 1
+        default: return
+        }
+
+        stacked := targetsAt(X, rx, ry, session.Incoming())
+        if len(stacked) == 0 { return }
+
+        // where the current target is in the stack. A target that isn't
+        // under the pointer anymore counts as just above the top
+        current := -1
+        for i, win := range stacked {
+            if win == session.Target() { current = i }
+        }
+        next := current + step
+        if current == -1 && step < 0 { next = len(stacked) - 1 }
+        next = (next + len(stacked)) % len(stacked)
+
+        if stacked[next] != session.Target() {
+            retarget(X, stacked[next], rx, ry)
         }
     }
 
     handleDragEnd := func(X *xgbutil.XUtil, rx, ry, ex, ey int) {
-        exit_early := false
-        // get icon we are dropping over
-        icon_win, _, err := wm.FindNextUnderMouse(X, cross.Id)
+        // the icon under the pointer on release is the one that counts
+        icon_win, _, err := wm.FindNextUnderMouse(X, cross_ui.Window.Id)
         if err != nil {
             log.Printf("DragEnd: icon not found: %v\n", err)
-            exit_early = true
+            icon_win = 0
         }
+        hoverIcon(icon_win)
 
-        incoming, target, err := dm.EndDrag()
-        // drag manager produces errors if we don't have both an Incoming and a Target yet
+        // a release anywhere but on an icon changes nothing
+        if session.State() == util.DragOverIcon {
+            err = session.Commit()
+        } else {
+            err = session.Cancel()
+        }
         if err != nil {
-            log.Printf("DragEnd: drag manager state error: %v\n", err)
-            exit_early = true
+            log.Printf("DragEnd: %v\n", err)
         }
+    }
 
+    // Escape or a second button: drop nothing, wherever the pointer is
+    handleDragCancel := func(X *xgbutil.XUtil) {
+        err := session.Cancel()
+        if err != nil {
+            log.Printf("DragCancel: %v\n", err)
+        }
+    }
 
-        // we tried: hide UI
-        cross.Unmap()
+    // the passive grabs the current bindings hold on the root window
+    var grabs []rootGrab
+
+    // (re)build everything that depends on the config: the cross, the
+    // mouse and key bindings on the root window, and the wm timeouts.
+    // On error, the previous config stays in place.
+    applyConfig := func(next *config.Config) error {
+        next_grabs, err := configGrabs(X, next)
+        if err != nil { return err }
+        next_cross, err := makeCross(X, next)
+        if err != nil { return err }
+        next_ghosts, err := makeGhosts(X, next, next_cross.Theme, 3)
+        if err != nil {
+            next_cross.Destroy()
+            return err
+        }
 
-        // we had some sort of error, escape!
-        if exit_early { return }
+        // make sure we can have every new binding before touching the old
+        // ones (see grabs.go)
+        err = takeGrabs(X, grabs, next_grabs)
+        if err != nil {
+            next_cross.Destroy()
+            for _, ghost := range next_ghosts {
+                ghost.Destroy()
+            }
+            return err
+        }
 
-        // retrieve the action that this icon indicates
-        if action, ok := win_to_action[icon_win]; ok {
-            // create util-window objects from our window IDs
-            if incoming_id, inc_ok := incoming.(xproto.Window); inc_ok {
-                inc_win := xwindow.New(X, incoming_id)
-                if target_id, t_ok := target.(xproto.Window); t_ok {
-                    t_win := xwindow.New(X, target_id)
+        // out with the old. A drag in progress goes with its binding.
+        mousebind.Detach(X, X.RootWin())
+        keybind.Detach(X, X.RootWin())
+        if move_drag != nil {
+            move_drag.Destroy()
+            move_drag = nil
+        }
+        if resize_drag != nil {
+            resize_drag.Destroy()
+            resize_drag = nil
+        }
+        if session.Active() {
+            session.Cancel()
+        }
+        if cross_ui != nil {
+            cross_ui.Destroy()
+        }
+        for _, ghost := range ghosts {
+            ghost.Destroy()
+        }
+        if outline != nil {
+            outline.Destroy()
+        }
+        // mousebind.Detach leaves the server's button grabs in place
+        releaseGrabs(X, grabs, next_grabs)
+        grabs = next_grabs
+
+        // in with the new
+        cross_ui = next_cross
+        win_to_action = mapActions(cross_ui)
+        win_to_plan = mapPlans(cross_ui)
+        // one ghost for each window an action can move, and one for the target
+        ghosts, outline = next_ghosts[:2], next_ghosts[2]
+        wm.MoveResizeTimeout = next.MoveResizeTimeout
+        conf = next
+
+        // we hold every grab these need, so they can only fail on a bug
+        move_drag, err = util.BindDrag(X, X.RootWin(), next.KeyComboMove,
+            handleDragStart,
+            handleDragStep,
+            handleDragEnd,
+            handleDragPress,
+            handleDragCancel)
+        if err != nil {
+            return fmt.Errorf("applyConfig: couldn't bind %s: %v", next.KeyComboMove, err)
+        }
 
-                    // perform the action!
-                    action(t_win, inc_win)
+        ///////////////////////////////////////////////////////////////////////
+        // Window resizing behavior spike
+        resize_drag, err = ManageResizingWindows(X, next)
+        if err != nil {
+            return fmt.Errorf("applyConfig: couldn't bind %s: %v", next.KeyComboResize, err)
+        }
 
-                } else {
-                    log.Println("DragEnd: target type error (was %v)\n", target)
-                }
-            } else {
-                log.Println("DragEnd: incoming type error (was %v)\n", incoming)
+        // keyboard versions of the cross's actions
+        return BindKeyActions(X, next)
+    }
+    fatal(applyConfig(conf))
+
+    // pick up config changes on SIGHUP, or when the file changes on disk
+    reload := WatchConfig(config.Path(), ConfigPollInterval)
+
+    // run the event loop ourselves, so that config reloads happen between
+    // X events instead of in the middle of handling one
+    pingBefore, pingAfter, pingQuit := xevent.MainPing(X)
+    for {
+        select {
+        case <-pingBefore:
+            // an event is being handled. wait for it to finish.
+            <-pingAfter
+        case <-reload:
+            next, err := config.Load(X)
+            if err != nil {
+                log.Printf("Reload: rejected new config, keeping the old one: %v\n", err)
+                continue
             }
-        } else {
-            log.Printf("DragEnd: couldn't map window %v to an action", icon_win)
+            err = applyConfig(next)
+            if err != nil {
+                log.Printf("Reload: couldn't apply new config, keeping the old one: %v\n", err)
+                continue
+            }
+            log.Printf("Reload: applied config from %s\n", config.Path())
+        case <-pingQuit:
+            return
         }
     }
-
-    mousebind.Drag(X, X.RootWin(), X.RootWin(), KeyComboMove, true, 
-        handleDragStart, 
-        handleDragStep, 
-        handleDragEnd)
-
-    ///////////////////////////////////////////////////////////////////////////
-    // Window resizing behavior spike
-    ManageResizingWindows(X)
-
-    // start event loop, even though we have no events
-    // to keep app from just closing
-    xevent.Main(X)
 }
-        
-
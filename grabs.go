@@ -0,0 +1,113 @@
+package main
+
+/*
+The passive grabs a config needs on the root window, so a reload can make
+sure it gets all of them before giving up the old config's bindings.
+
+xgbutil can only detach bindings a whole window at a time, so the new
+bindings can't be connected alongside the old ones and the losers thrown
+away. Instead we take the raw grabs first: X lets a client grab a
+combination it already holds, so the old bindings keep working throughout.
+Once every grab has succeeded, rebinding can only be refused if another
+program grabs a key in the moment between keybind.Detach releasing it and
+BindKeyActions taking it back.
+*/
+
+import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/keybind"
+    "github.com/BurntSushi/xgbutil/mousebind"
+
+    "github.com/justjake/j3/config"
+
+    "fmt"
+)
+
+// one passive grab on the root window: a button, or a key
+type rootGrab struct {
+    combo   string
+    mods    uint16
+    // zero for key grabs
+    button  xproto.Button
+    keycode xproto.Keycode
+}
+
+// the same grab, whatever combo string it came from
+func (g rootGrab) same(other rootGrab) bool {
+    return g.mods == other.mods && g.button == other.button && g.keycode == other.keycode
+}
+
+func (g rootGrab) take(X *xgbutil.XUtil) error {
+    var err error
+    if g.button != 0 {
+        err = mousebind.GrabChecked(X, X.RootWin(), g.mods, g.button, false)
+    } else {
+        err = keybind.GrabChecked(X, X.RootWin(), g.mods, g.keycode)
+    }
+    if err != nil {
+        return fmt.Errorf("couldn't grab %s, is another program using it? (%v)", g.combo, err)
+    }
+    return nil
+}
+
+func (g rootGrab) release(X *xgbutil.XUtil) {
+    if g.button != 0 {
+        mousebind.Ungrab(X, X.RootWin(), g.mods, g.button)
+    } else {
+        keybind.Ungrab(X, X.RootWin(), g.mods, g.keycode)
+    }
+}
+
+// every grab the bindings in `conf` need
+func configGrabs(X *xgbutil.XUtil, conf *config.Config) ([]rootGrab, error) {
+    grabs := []rootGrab{}
+    for _, combo := range []string{conf.KeyComboMove, conf.KeyComboResize} {
+        mods, button, err := mousebind.ParseString(X, combo)
+        if err != nil { return nil, err }
+        grabs = append(grabs, rootGrab{combo: combo, mods: mods, button: button})
+    }
+    for _, combo := range keyCombos(conf) {
+        mods, keycodes, err := keybind.ParseString(X, combo)
+        if err != nil { return nil, err }
+        for _, keycode := range keycodes {
+            grabs = append(grabs, rootGrab{combo: combo, mods: mods, keycode: keycode})
+        }
+    }
+    return grabs, nil
+}
+
+func hasGrab(grabs []rootGrab, g rootGrab) bool {
+    for _, other := range grabs {
+        if other.same(g) { return true }
+    }
+    return false
+}
+
+// Take every grab in `next` that isn't in `held` already. If one of them
+// fails, the ones taken so far are released again, leaving `held` just as
+// it was.
+func takeGrabs(X *xgbutil.XUtil, held, next []rootGrab) error {
+    taken := []rootGrab{}
+    for _, g := range next {
+        if hasGrab(held, g) || hasGrab(taken, g) { continue }
+        err := g.take(X)
+        if err != nil {
+            for _, t := range taken {
+                t.release(X)
+            }
+            return err
+        }
+        taken = append(taken, g)
+    }
+    return nil
+}
+
+// release the grabs in `old` that `next` doesn't use
+func releaseGrabs(X *xgbutil.XUtil, old, next []rootGrab) {
+    for _, g := range old {
+        if !hasGrab(next, g) {
+            g.release(X)
+        }
+    }
+}
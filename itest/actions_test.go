@@ -0,0 +1,56 @@
+package itest
+
+/* actions_test.go
+   The window actions, called straight from the wm package. These don't
+   need j3 running.
+   */
+import (
+    "github.com/BurntSushi/xgbutil/xrect"
+
+    "github.com/justjake/j3/wm"
+
+    "testing"
+)
+
+// a target, and an incoming window off to its right
+var (
+    smallTarget   = xrect.New(100, 100, 400, 300)
+    smallIncoming = xrect.New(700, 100, 300, 200)
+)
+
+func TestActions(t *testing.T) {
+    tests := []struct {
+        name    string
+        action  wm.WindowInteraction
+        want_t  xrect.Rect
+        want_i  xrect.Rect
+    }{
+        {"SplitLeft", wm.SplitLeft,
+            xrect.New(300, 100, 200, 300),
+            xrect.New(100, 100, 200, 300)},
+        {"SplitBottom", wm.SplitBottom,
+            xrect.New(100, 100, 400, 150),
+            xrect.New(100, 250, 400, 150)},
+        {"Swap", wm.Swap,
+            xrect.New(700, 100, 300, 200),
+            xrect.New(100, 100, 400, 300)},
+        {"ShoveRight", wm.ShoveRight,
+            xrect.New(100, 100, 400, 300),
+            xrect.New(500, 100, 300, 300)},
+        // there's only 100px left of the target, so the incoming window has
+        // to shrink to stay on screen
+        {"ShoveLeft/Clipped", wm.ShoveLeft,
+            xrect.New(100, 100, 400, 300),
+            xrect.New(0, 100, 100, 300)},
+    }
+
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            h := harness(t)
+            target, incoming := h.pair(t, smallTarget, smallIncoming)
+            err := test.action(target, incoming)
+            if err != nil { t.Fatal(err) }
+            h.expectFrames(t, target, incoming, test.want_t, test.want_i)
+        })
+    }
+}
@@ -0,0 +1,145 @@
+package itest
+
+/* cross.go
+   Find j3's cross and its icons on screen, and drag windows onto them.
+   The cross and each icon carry a WM_NAME (see ui.CrossName), which is how
+   we tell them apart from everything else on the root window.
+   */
+import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil/icccm"
+    "github.com/BurntSushi/xgbutil/xrect"
+
+    "github.com/justjake/j3/ui"
+    "github.com/justjake/j3/wm"
+
+    "fmt"
+    "image"
+)
+
+// the child of `parent` named `name`. With `mapped`, only a visible one
+// will do.
+func (h *Harness) findChild(parent xproto.Window, name string, mapped bool) (xproto.Window, error) {
+    tree, err := xproto.QueryTree(h.X.Conn(), parent).Reply()
+    if err != nil { return 0, err }
+
+    for _, child := range tree.Children {
+        got, err := icccm.WmNameGet(h.X, child)
+        if err != nil || got != name { continue }
+        if mapped {
+            attrs, err := xproto.GetWindowAttributes(h.X.Conn(), child).Reply()
+            if err != nil || attrs.MapState != xproto.MapStateViewable { continue }
+        }
+        return child, nil
+    }
+    return 0, fmt.Errorf("no window named %q", name)
+}
+
+func (h *Harness) findTopLevel(name string, mapped bool) (xproto.Window, error) {
+    return h.findChild(h.X.RootWin(), name, mapped)
+}
+
+// Wait for the cross to show up, and find the middle of icon `name` on it,
+// in root coordinates
+func (h *Harness) IconCenter(name string) (image.Point, error) {
+    var center image.Point
+    err := h.waitFor("icon " + name, func() (bool, error) {
+        cross, err := h.findTopLevel(ui.CrossName, true)
+        if err != nil { return false, nil }
+        icon, err := h.findChild(cross, name, true)
+        if err != nil { return false, nil }
+
+        geom, err := xproto.GetGeometry(h.X.Conn(), xproto.Drawable(icon)).Reply()
+        if err != nil { return false, err }
+        x, y, err := wm.TranslateCoordinatesSync(h.X, icon, h.X.RootWin(),
+            int(geom.Width) / 2, int(geom.Height) / 2)
+        if err != nil { return false, err }
+        center = image.Pt(x, y)
+        return true, nil
+    })
+    return center, err
+}
+
+// Press j3's move button on the window whose frame is `incoming`, and drag
+// it over icon `name` on the cross over the one whose frame is `target`
+func (h *Harness) dragToIcon(incoming, target xrect.Rect, name string) error {
+    p := h.Pointer
+    err := p.MoveTo(Center(incoming))
+    if err != nil { return err }
+    err = p.Press(1)
+    if err != nil { return err }
+
+    err = p.Glide(Center(target))
+    if err != nil { return err }
+    icon, err := h.IconCenter(name)
+    if err != nil { return err }
+    return p.Glide(icon)
+}
+
+// Drag the window whose frame is `incoming` over the one whose frame is
+// `target`, and drop it on icon `name`, with j3's move button
+func (h *Harness) DropOn(incoming, target xrect.Rect, name string) error {
+    err := h.dragToIcon(incoming, target, name)
+    // let go no matter what, so one failed drag doesn't wreck the next case
+    release_err := h.Pointer.Release(1)
+    if err != nil { return err }
+    return release_err
+}
+
+// Like DropOn, but click `button` over the icon before letting go, which
+// should call the drag off. The cross has to go away before the move button
+// comes up, or it's the release that hid it.
+func (h *Harness) CancelOn(incoming, target xrect.Rect, name string, button int) error {
+    p := h.Pointer
+    err := h.dragToIcon(incoming, target, name)
+    if err == nil {
+        err = p.Press(button)
+    }
+    if err == nil {
+        err = p.Release(button)
+    }
+    if err == nil {
+        err = h.waitCrossHidden()
+    }
+    release_err := p.Release(1)
+    if err != nil { return err }
+    return release_err
+}
+
+// Like DropOn, but carry on past the icon to `desktop`, somewhere with no
+// window under it, and let go there. The cross should go away on the way.
+func (h *Harness) DropOnDesktop(incoming, target xrect.Rect, name string, desktop image.Point) error {
+    p := h.Pointer
+    err := h.dragToIcon(incoming, target, name)
+    if err == nil {
+        err = p.Glide(desktop)
+    }
+    if err == nil {
+        err = h.waitCrossHidden()
+    }
+    release_err := p.Release(1)
+    if err != nil { return err }
+    return release_err
+}
+
+func (h *Harness) waitCrossHidden() error {
+    return h.waitFor("the cross to hide", func() (bool, error) {
+        _, err := h.findTopLevel(ui.CrossName, true)
+        return err != nil, nil
+    })
+}
+
+// Drag from `from` to `to` with j3's resize button
+func (h *Harness) ResizeDrag(from, to image.Point) error {
+    p := h.Pointer
+    err := p.MoveTo(from)
+    if err != nil { return err }
+    err = p.Press(3)
+    if err != nil { return err }
+    err = p.Glide(to)
+    if err != nil {
+        p.Release(3)
+        return err
+    }
+    return p.Release(3)
+}
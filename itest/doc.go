@@ -0,0 +1,18 @@
+/*
+Package itest runs j3 against a real X server, end to end.
+
+Each run starts a headless Xvfb, puts a small scripted EWMH window manager
+on it (StubWM), creates client windows, and then either calls the wm
+package's actions directly or drives a running j3 binary with synthetic
+XTEST mouse input. Tests check the frame geometry every window ends up
+with.
+
+The tests are run by go test, and pass the j3 binary to drive with -j3:
+
+    $ go build github.com/justjake/j3
+    $ go test github.com/justjake/j3/itest -args -j3 $PWD/j3
+
+Without -j3, the drag tests are skipped. Without Xvfb on $PATH, or an X
+server with XTEST, they all are.
+*/
+package itest
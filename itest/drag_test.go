@@ -0,0 +1,81 @@
+package itest
+
+/* drag_test.go
+   Drive a running j3 with the fake mouse, the way a user would. These are
+   skipped without -j3.
+   */
+import (
+    "github.com/BurntSushi/xgbutil/xrect"
+
+    "image"
+    "testing"
+)
+
+// a target big enough for the full cross, and an incoming window off to its
+// right
+var (
+    bigTarget   = xrect.New(100, 100, 600, 400)
+    bigIncoming = xrect.New(800, 100, 300, 200)
+)
+
+// drag bigIncoming onto each icon over bigTarget
+func TestDrag(t *testing.T) {
+    tests := []struct {
+        icon    string
+        want_t  xrect.Rect
+        want_i  xrect.Rect
+    }{
+        {"Swap",
+            xrect.New(800, 100, 300, 200),
+            xrect.New(100, 100, 600, 400)},
+        {"SplitRight",
+            xrect.New(100, 100, 300, 400),
+            xrect.New(400, 100, 300, 400)},
+        {"ShoveBottom",
+            xrect.New(100, 100, 600, 400),
+            xrect.New(100, 500, 600, 200)},
+    }
+
+    for _, test := range tests {
+        t.Run(test.icon, func(t *testing.T) {
+            h := harnessWithJ3(t)
+            target, incoming := h.pair(t, bigTarget, bigIncoming)
+            err := h.DropOn(bigIncoming, bigTarget, test.icon)
+            if err != nil { t.Fatal(err) }
+            h.expectFrames(t, target, incoming, test.want_t, test.want_i)
+        })
+    }
+}
+
+// right click over the swap icon before letting go: nothing moves
+func TestDragCancel(t *testing.T) {
+    h := harnessWithJ3(t)
+    target, incoming := h.pair(t, bigTarget, bigIncoming)
+    err := h.CancelOn(bigIncoming, bigTarget, "Swap", 3)
+    if err != nil { t.Fatal(err) }
+    h.expectFrames(t, target, incoming, bigTarget, bigIncoming)
+}
+
+// drag across the swap icon and off onto the empty bottom of the screen:
+// nothing moves
+func TestDragToDesktop(t *testing.T) {
+    h := harnessWithJ3(t)
+    target, incoming := h.pair(t, bigTarget, bigIncoming)
+    err := h.DropOnDesktop(bigIncoming, bigTarget, "Swap", image.Pt(640, 700))
+    if err != nil { t.Fatal(err) }
+    h.expectFrames(t, target, incoming, bigTarget, bigIncoming)
+}
+
+// two windows side by side. Dragging near the shared edge moves it, and
+// resizes both windows.
+func TestSeamResize(t *testing.T) {
+    h := harnessWithJ3(t)
+    left, right := h.pair(t, xrect.New(100, 100, 400, 300), xrect.New(500, 100, 300, 300))
+
+    // just inside the left window's right edge, so j3 picks that edge
+    err := h.ResizeDrag(image.Pt(495, 250), image.Pt(545, 250))
+    if err != nil { t.Fatal(err) }
+    h.expectFrames(t, left, right,
+        xrect.New(100, 100, 450, 300),
+        xrect.New(550, 100, 250, 300))
+}
@@ -0,0 +1,254 @@
+package itest
+
+/* harness.go
+   Everything a test needs: an X server, a window manager, client
+   windows to push around, a fake mouse, and optionally a running j3.
+   */
+import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/ewmh"
+    "github.com/BurntSushi/xgbutil/icccm"
+    "github.com/BurntSushi/xgbutil/xrect"
+    "github.com/BurntSushi/xgbutil/xwindow"
+
+    "github.com/justjake/j3/ui"
+    "github.com/justjake/j3/util"
+    "github.com/justjake/j3/wm"
+
+    "fmt"
+    "image"
+    "io/ioutil"
+    logLib "log"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "time"
+)
+
+var (
+    log = logLib.New(os.Stderr, "[itest] ", logLib.LstdFlags | logLib.Lshortfile)
+)
+
+// how long to wait for windows to get where they're going
+var SettleTimeout = time.Second
+
+// j3 grabs its mouse bindings a moment after it creates the cross. There's
+// no way to ask the server about passive grabs, so we just wait this long.
+var J3GrabDelay = time.Millisecond * 200
+
+// The config the harness runs j3 with. Plain buttons, so the fake mouse
+// doesn't need to hold down modifier keys.
+const J3Config = `
+key_combo_move      = "1"
+key_combo_resize    = "3"
+adjacency_epsilon   = 6
+dynamic_drag_resize = false
+icon_scale          = 1.0
+`
+
+// Start's error when this machine can't run the integration tests at all,
+// as opposed to the tests going wrong
+type UnavailableError struct {
+    // what's missing
+    What    string
+    Err     error
+}
+
+func (err *UnavailableError) Error() string {
+    return fmt.Sprintf("no %s: %v", err.What, err.Err)
+}
+
+type Harness struct {
+    Xvfb    *Xvfb
+    WM      *StubWM
+    // the harness's own connection, for clients and direct wm calls
+    X       *xgbutil.XUtil
+    Pointer *Pointer
+
+    // scratch space for j3's config and log
+    Dir     string
+    j3      *exec.Cmd
+    clients []*xwindow.Window
+}
+
+// Start an X server with a `width` x `height` screen and a stub window
+// manager on it
+func Start(width, height int) (h *Harness, err error) {
+    h = &Harness{}
+    defer func() {
+        if err != nil {
+            h.Stop()
+            h = nil
+        }
+    }()
+
+    _, err = exec.LookPath("Xvfb")
+    if err != nil {
+        err = &UnavailableError{"Xvfb", err}
+        return
+    }
+
+    h.Dir, err = ioutil.TempDir("", "j3-itest")
+    if err != nil { return }
+
+    h.Xvfb, err = StartXvfb(width, height)
+    if err != nil { return }
+
+    h.WM, err = StartStubWM(h.Xvfb.Display)
+    if err != nil { return }
+
+    h.X, err = xgbutil.NewConnDisplay(h.Xvfb.Display)
+    if err != nil { return }
+
+    h.Pointer, err = NewPointer(h.X)
+    if err != nil {
+        err = &UnavailableError{"XTEST extension", err}
+    }
+    return
+}
+
+// tear everything down, j3 first. Safe to call more than once.
+func (h *Harness) Stop() {
+    h.StopJ3()
+    if h.X != nil {
+        h.X.Conn().Close()
+        h.X = nil
+    }
+    if h.WM != nil {
+        h.WM.Stop()
+        h.WM = nil
+    }
+    if h.Xvfb != nil {
+        h.Xvfb.Stop()
+        h.Xvfb = nil
+    }
+    if h.Dir != "" {
+        os.RemoveAll(h.Dir)
+        h.Dir = ""
+    }
+}
+
+// where j3's output goes
+func (h *Harness) J3Log() string {
+    return filepath.Join(h.Dir, "j3.log")
+}
+
+// Run the j3 binary at `binary` against the harness's display, with
+// J3Config, and wait until it's ready for drags
+func (h *Harness) StartJ3(binary string) error {
+    conf_dir := filepath.Join(h.Dir, "config", "j3")
+    err := os.MkdirAll(conf_dir, 0755)
+    if err != nil { return err }
+    err = ioutil.WriteFile(filepath.Join(conf_dir, "config.toml"), []byte(J3Config), 0644)
+    if err != nil { return err }
+
+    out, err := os.Create(h.J3Log())
+    if err != nil { return err }
+    defer out.Close()
+
+    cmd := exec.Command(binary)
+    cmd.Env = append(os.Environ(),
+        "DISPLAY=" + h.Xvfb.Display,
+        "XDG_CONFIG_HOME=" + filepath.Join(h.Dir, "config"))
+    cmd.Stdout = out
+    cmd.Stderr = out
+    err = cmd.Start()
+    if err != nil { return err }
+    h.j3 = cmd
+
+    // the cross is created right before the bindings are grabbed
+    err = h.waitFor("j3 to create its cross", func() (bool, error) {
+        _, err := h.findTopLevel(ui.CrossName, false)
+        return err == nil, nil
+    })
+    if err != nil {
+        h.StopJ3()
+        return fmt.Errorf("StartJ3: %v (see %s)", err, h.J3Log())
+    }
+    time.Sleep(J3GrabDelay)
+    return nil
+}
+
+func (h *Harness) StopJ3() {
+    if h.j3 == nil { return }
+    h.j3.Process.Kill()
+    h.j3.Wait()
+    h.j3 = nil
+}
+
+// Create and map a client window, sized so its frame lands on `frame`
+func (h *Harness) NewClient(name string, frame xrect.Rect) (*xwindow.Window, error) {
+    win, err := xwindow.Generate(h.X)
+    if err != nil { return nil, err }
+
+    ext := StubExtents
+    width, height := ext.ClientSize(frame.Width(), frame.Height())
+    err = win.CreateChecked(h.X.RootWin(), frame.X(), frame.Y(), width, height,
+        xproto.CwBackPixel, 0xffffff)
+    if err != nil { return nil, err }
+    icccm.WmNameSet(h.X, win.Id, name)
+    win.Map()
+    h.clients = append(h.clients, win)
+
+    // wait for the window manager to frame the client, so wm reads its
+    // extents instead of measuring a window that has no frame yet
+    err = h.waitFor(name + " to be framed", func() (bool, error) {
+        _, err := ewmh.FrameExtentsGet(h.X, win.Id)
+        return err == nil, nil
+    })
+    if err != nil { return nil, err }
+    err = h.waitFor(name + " to be placed", func() (bool, error) {
+        got, err := wm.FrameGeometry(win)
+        if err != nil { return false, nil }
+        return util.RectEquals(got, frame), nil
+    })
+    if err != nil { return nil, err }
+    return win, nil
+}
+
+// get rid of every client, for a fresh start
+func (h *Harness) Reset() {
+    for _, win := range h.clients {
+        wm.ForgetFrameExtents(win.Id)
+        win.Destroy()
+    }
+    h.clients = nil
+    h.X.Sync()
+}
+
+// Wait for the frame of `win` to be at `want`
+func (h *Harness) ExpectFrame(win *xwindow.Window, want xrect.Rect) error {
+    var got xrect.Rect
+    err := h.waitFor("a frame change", func() (bool, error) {
+        var err error
+        got, err = wm.FrameGeometry(win)
+        if err != nil { return false, err }
+        return util.RectEquals(got, want), nil
+    })
+    if err != nil {
+        name, _ := icccm.WmNameGet(h.X, win.Id)
+        return fmt.Errorf("%s: frame is %v, want %v", name, got, want)
+    }
+    return nil
+}
+
+// poll `done` until it says yes, or SettleTimeout passes
+func (h *Harness) waitFor(what string, done func() (bool, error)) error {
+    timeout := time.After(SettleTimeout)
+    for {
+        ok, err := done()
+        if err != nil { return err }
+        if ok { return nil }
+        select {
+        case <-timeout:
+            return fmt.Errorf("timed out waiting for %s", what)
+        case <-time.After(time.Millisecond * 10):
+        }
+    }
+}
+
+// the middle of a window's frame, where a drag should grab it
+func Center(rect xrect.Rect) image.Point {
+    return image.Pt(rect.X() + rect.Width() / 2, rect.Y() + rect.Height() / 2)
+}
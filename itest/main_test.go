@@ -0,0 +1,105 @@
+package itest
+
+/* main_test.go
+   One X server, window manager and j3 for the whole run, started the first
+   time a test asks for them. Every test starts from an empty screen and
+   cleans up after itself with Harness.Reset.
+
+   The expected geometries in the tests are worked out by hand for the stub
+   window manager on a ScreenWidth x ScreenHeight screen, so a change in the
+   layout math shows up here as a failure, not as a new "right" answer.
+   */
+import (
+    "github.com/BurntSushi/xgbutil/xrect"
+    "github.com/BurntSushi/xgbutil/xwindow"
+
+    "flag"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "sync"
+    "testing"
+)
+
+// the screen every test runs on
+const (
+    ScreenWidth  = 1280
+    ScreenHeight = 800
+)
+
+var j3Binary = flag.String("j3", "", "path to a j3 binary, for the drag tests")
+
+var (
+    startOnce   sync.Once
+    shared      *Harness
+    sharedErr   error
+    j3Err       error
+)
+
+func TestMain(m *testing.M) {
+    flag.Parse()
+    code := m.Run()
+
+    if shared != nil {
+        if code != 0 && *j3Binary != "" {
+            // the log goes away with the harness's scratch directory
+            if log, err := ioutil.ReadFile(shared.J3Log()); err == nil {
+                fmt.Printf("\nj3's log:\n%s", log)
+            }
+        }
+        shared.Stop()
+    }
+    os.Exit(code)
+}
+
+// The shared harness, started if it isn't yet. Skips the test if there's
+// no Xvfb or XTEST to run it with. The screen is reset after the test.
+func harness(t *testing.T) *Harness {
+    startOnce.Do(func() {
+        shared, sharedErr = Start(ScreenWidth, ScreenHeight)
+        if sharedErr == nil && *j3Binary != "" {
+            j3Err = shared.StartJ3(*j3Binary)
+        }
+    })
+
+    if _, ok := sharedErr.(*UnavailableError); ok {
+        t.Skipf("can't run against X: %v", sharedErr)
+    }
+    if sharedErr != nil {
+        t.Fatalf("couldn't start the harness: %v", sharedErr)
+    }
+    t.Cleanup(shared.Reset)
+    return shared
+}
+
+// Like harness, for tests that drive a running j3. Skips the test if there's
+// no -j3 to run.
+func harnessWithJ3(t *testing.T) *Harness {
+    h := harness(t)
+    if *j3Binary == "" {
+        t.Skip("needs a j3 binary: go test ./itest -args -j3 /path/to/j3")
+    }
+    if j3Err != nil {
+        t.Fatalf("couldn't start j3: %v", j3Err)
+    }
+    return h
+}
+
+// make a target and an incoming window
+func (h *Harness) pair(t *testing.T, target, incoming xrect.Rect) (*xwindow.Window, *xwindow.Window) {
+    t.Helper()
+    target_win, err := h.NewClient("target", target)
+    if err != nil { t.Fatal(err) }
+    incoming_win, err := h.NewClient("incoming", incoming)
+    if err != nil { t.Fatal(err) }
+    return target_win, incoming_win
+}
+
+// wait for both windows to end up where they should
+func (h *Harness) expectFrames(t *testing.T, target, incoming *xwindow.Window, want_t, want_i xrect.Rect) {
+    t.Helper()
+    err := h.ExpectFrame(target, want_t)
+    if err != nil { t.Error(err) }
+    err = h.ExpectFrame(incoming, want_i)
+    if err != nil { t.Error(err) }
+}
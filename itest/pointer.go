@@ -0,0 +1,74 @@
+package itest
+
+/* pointer.go
+   Synthetic mouse input through the XTEST extension. The server treats
+   these exactly like a real mouse, so passive grabs (like j3's drag
+   bindings) activate just as they would for the user.
+   */
+import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgb/xtest"
+    "github.com/BurntSushi/xgbutil"
+
+    "image"
+    "time"
+)
+
+// how long to pause between motion events, so j3 sees a drag instead of a
+// teleport
+var StepDelay = time.Millisecond * 10
+
+// how many motion events to send when gliding from one point to another
+const glideSteps = 8
+
+// A fake mouse
+type Pointer struct {
+    X   *xgbutil.XUtil
+    // where the pointer is, in root coordinates
+    Pos image.Point
+}
+
+func NewPointer(X *xgbutil.XUtil) (*Pointer, error) {
+    err := xtest.Init(X.Conn())
+    if err != nil { return nil, err }
+    return &Pointer{X: X}, nil
+}
+
+func (p *Pointer) fake(kind byte, detail byte, x, y int) error {
+    return xtest.FakeInputChecked(p.X.Conn(), kind, detail, 0,
+        p.X.RootWin(), int16(x), int16(y), 0).Check()
+}
+
+// jump straight to `to`
+func (p *Pointer) MoveTo(to image.Point) error {
+    err := p.fake(xproto.MotionNotify, 0, to.X, to.Y)
+    if err != nil { return err }
+    p.Pos = to
+    return nil
+}
+
+// move to `to` in a few steps, like a hand would
+func (p *Pointer) Glide(to image.Point) error {
+    from := p.Pos
+    for i := 1; i <= glideSteps; i++ {
+        step := image.Pt(
+            from.X + (to.X - from.X) * i / glideSteps,
+            from.Y + (to.Y - from.Y) * i / glideSteps)
+        err := p.MoveTo(step)
+        if err != nil { return err }
+        time.Sleep(StepDelay)
+    }
+    return nil
+}
+
+func (p *Pointer) Press(button int) error {
+    err := p.fake(xproto.ButtonPress, byte(button), 0, 0)
+    time.Sleep(StepDelay)
+    return err
+}
+
+func (p *Pointer) Release(button int) error {
+    err := p.fake(xproto.ButtonRelease, byte(button), 0, 0)
+    time.Sleep(StepDelay)
+    return err
+}
@@ -0,0 +1,396 @@
+package itest
+
+/* stubwm.go
+   Just enough of an EWMH window manager for j3 to work against:
+
+    * every client is reparented into a frame with StubExtents decorations,
+      and _NET_FRAME_EXTENTS says so
+    * _NET_CLIENT_LIST and _NET_CLIENT_LIST_STACKING follow the clients,
+      in mapping order
+    * ConfigureRequests and _NET_MOVERESIZE_WINDOW move and resize frames,
+      honoring NorthWest and Static gravity
+    * _NET_WORKAREA covers the whole screen
+
+   There is no focus, no stacking beyond "newest on top", and no desktops.
+   */
+import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/ewmh"
+    "github.com/BurntSushi/xgbutil/icccm"
+    "github.com/BurntSushi/xgbutil/xevent"
+    "github.com/BurntSushi/xgbutil/xprop"
+    "github.com/BurntSushi/xgbutil/xrect"
+    "github.com/BurntSushi/xgbutil/xwindow"
+
+    "github.com/justjake/j3/plan"
+
+    "fmt"
+    "sync"
+)
+
+// the decorations the stub window manager puts around every client
+var StubExtents = plan.Extents{Left: 2, Right: 2, Top: 18, Bottom: 2}
+
+// what the stub window manager claims to support
+var stubSupported = []string{
+    "_NET_SUPPORTED",
+    "_NET_SUPPORTING_WM_CHECK",
+    "_NET_WM_NAME",
+    "_NET_CLIENT_LIST",
+    "_NET_CLIENT_LIST_STACKING",
+    "_NET_NUMBER_OF_DESKTOPS",
+    "_NET_CURRENT_DESKTOP",
+    "_NET_DESKTOP_GEOMETRY",
+    "_NET_WORKAREA",
+    "_NET_FRAME_EXTENTS",
+    "_NET_REQUEST_FRAME_EXTENTS",
+    "_NET_MOVERESIZE_WINDOW",
+}
+
+// A scripted window manager, running on its own X connection
+type StubWM struct {
+    X       *xgbutil.XUtil
+    check   *xwindow.Window
+
+    lock    sync.Mutex
+    clients map[xproto.Window]*stubClient
+    // managed clients, bottom to top
+    order   []xproto.Window
+}
+
+type stubClient struct {
+    client  *xwindow.Window
+    frame   *xwindow.Window
+}
+
+// Connect to `display` and start managing windows on it. Fails if another
+// window manager is already running there.
+func StartStubWM(display string) (*StubWM, error) {
+    X, err := xgbutil.NewConnDisplay(display)
+    if err != nil { return nil, err }
+
+    s := &StubWM{X: X, clients: make(map[xproto.Window]*stubClient)}
+    err = s.setup()
+    if err != nil {
+        X.Conn().Close()
+        return nil, err
+    }
+
+    go xevent.Main(X)
+    return s, nil
+}
+
+// take over the root window and publish the EWMH properties
+func (s *StubWM) setup() error {
+    X := s.X
+    root := xwindow.New(X, X.RootWin())
+
+    // only one client can redirect the root's substructure: this is what
+    // makes us the window manager
+    err := root.Listen(xproto.EventMaskSubstructureRedirect | xproto.EventMaskSubstructureNotify)
+    if err != nil {
+        return fmt.Errorf("StartStubWM: is another window manager running? %v", err)
+    }
+
+    s.check, err = xwindow.Generate(X)
+    if err != nil { return err }
+    s.check.Create(X.RootWin(), -1, -1, 1, 1, 0)
+
+    geom := xwindow.RootGeometry(X)
+    steps := []func() error{
+        func() error { return ewmh.SupportingWmCheckSet(X, X.RootWin(), s.check.Id) },
+        func() error { return ewmh.SupportingWmCheckSet(X, s.check.Id, s.check.Id) },
+        func() error { return ewmh.WmNameSet(X, s.check.Id, "j3-stub") },
+        func() error { return ewmh.SupportedSet(X, stubSupported) },
+        func() error { return ewmh.NumberOfDesktopsSet(X, 1) },
+        func() error { return ewmh.CurrentDesktopSet(X, 0) },
+        func() error {
+            return ewmh.DesktopGeometrySet(X, &ewmh.DesktopGeometry{Width: geom.Width(), Height: geom.Height()})
+        },
+        func() error {
+            return ewmh.WorkareaSet(X, []ewmh.Workarea{{X: 0, Y: 0, Width: uint(geom.Width()), Height: uint(geom.Height())}})
+        },
+        s.publishClients,
+    }
+    for _, step := range steps {
+        if err := step(); err != nil { return err }
+    }
+
+    xevent.MapRequestFun(s.handleMapRequest).Connect(X, X.RootWin())
+    xevent.ConfigureRequestFun(s.handleConfigureRequest).Connect(X, X.RootWin())
+    xevent.ClientMessageFun(s.handleClientMessage).Connect(X, X.RootWin())
+    return nil
+}
+
+// stop managing windows and close the connection. Clients are left where
+// they are.
+func (s *StubWM) Stop() {
+    xevent.Quit(s.X)
+    s.X.Conn().Close()
+}
+
+// update _NET_CLIENT_LIST and _NET_CLIENT_LIST_STACKING from s.order
+func (s *StubWM) publishClients() error {
+    s.lock.Lock()
+    order := append([]xproto.Window{}, s.order...)
+    s.lock.Unlock()
+
+    err := ewmh.ClientListSet(s.X, order)
+    if err != nil { return err }
+    return ewmh.ClientListStackingSet(s.X, order)
+}
+
+func (s *StubWM) lookup(win xproto.Window) (*stubClient, bool) {
+    s.lock.Lock()
+    defer s.lock.Unlock()
+    c, ok := s.clients[win]
+    return c, ok
+}
+
+// frame and map a new client. The frame goes where the client asked to be,
+// so a client created at x, y has its frame's top-left corner there.
+func (s *StubWM) handleMapRequest(X *xgbutil.XUtil, ev xevent.MapRequestEvent) {
+    if c, ok := s.lookup(ev.Window); ok {
+        // already managed, and being remapped
+        c.client.Map()
+        return
+    }
+
+    client := xwindow.New(X, ev.Window)
+    geom, err := client.Geometry()
+    if err != nil {
+        log.Printf("StubWM: can't manage %v: %v\n", ev.Window, err)
+        return
+    }
+
+    ext := StubExtents
+    frame, err := xwindow.Generate(X)
+    if err != nil {
+        log.Printf("StubWM: can't frame %v: %v\n", ev.Window, err)
+        return
+    }
+    w, h := ext.FrameSize(geom.Width(), geom.Height())
+    frame.Create(X.RootWin(), geom.X(), geom.Y(), w, h, 0)
+    // hear about the client's requests, and about it going away, through
+    // the frame
+    frame.Listen(xproto.EventMaskSubstructureRedirect | xproto.EventMaskSubstructureNotify)
+
+    // if the stub dies, the server gives clients back to the root window
+    xproto.ChangeSaveSet(X.Conn(), xproto.SetModeInsert, client.Id)
+    xproto.ReparentWindow(X.Conn(), client.Id, frame.Id, int16(ext.Left), int16(ext.Top))
+    ewmh.FrameExtentsSet(X, client.Id, &ewmh.FrameExtents{
+        Left: ext.Left, Right: ext.Right, Top: ext.Top, Bottom: ext.Bottom,
+    })
+
+    c := &stubClient{client, frame}
+    s.lock.Lock()
+    s.clients[client.Id] = c
+    s.order = append(s.order, client.Id)
+    s.lock.Unlock()
+
+    xevent.ConfigureRequestFun(s.handleConfigureRequest).Connect(X, frame.Id)
+    xevent.UnmapNotifyFun(func(X *xgbutil.XUtil, ev xevent.UnmapNotifyEvent) {
+        s.unmanage(c, true)
+    }).Connect(X, client.Id)
+    xevent.DestroyNotifyFun(func(X *xgbutil.XUtil, ev xevent.DestroyNotifyEvent) {
+        s.unmanage(c, false)
+    }).Connect(X, client.Id)
+
+    client.Map()
+    frame.Map()
+    frame.Stack(xproto.StackModeAbove)
+    err = s.publishClients()
+    if err != nil {
+        log.Printf("StubWM: couldn't publish the client list: %v\n", err)
+    }
+}
+
+// forget a client that was unmapped or destroyed. Unmapped clients get
+// their window back, outside of the frame.
+func (s *StubWM) unmanage(c *stubClient, reparent bool) {
+    s.lock.Lock()
+    if _, ok := s.clients[c.client.Id]; !ok {
+        s.lock.Unlock()
+        return
+    }
+    delete(s.clients, c.client.Id)
+    for i, win := range s.order {
+        if win == c.client.Id {
+            s.order = append(s.order[:i], s.order[i+1:]...)
+            break
+        }
+    }
+    s.lock.Unlock()
+
+    X := s.X
+    xevent.Detach(X, c.client.Id)
+    xevent.Detach(X, c.frame.Id)
+    if reparent {
+        frame, err := c.frame.Geometry()
+        if err == nil {
+            xproto.ReparentWindow(X.Conn(), c.client.Id, X.RootWin(), int16(frame.X()), int16(frame.Y()))
+        }
+        if atom, err := xprop.Atm(X, "_NET_FRAME_EXTENTS"); err == nil {
+            xproto.DeleteProperty(X.Conn(), c.client.Id, atom)
+        }
+    }
+    c.frame.Destroy()
+
+    err := s.publishClients()
+    if err != nil {
+        log.Printf("StubWM: couldn't publish the client list: %v\n", err)
+    }
+}
+
+// managed clients are moved by moving their frame. Anything else gets
+// exactly what it asked for.
+func (s *StubWM) handleConfigureRequest(X *xgbutil.XUtil, ev xevent.ConfigureRequestEvent) {
+    c, ok := s.lookup(ev.Window)
+    if !ok {
+        passConfigureRequest(X, ev)
+        return
+    }
+
+    const (
+        flagX = 1 << iota
+        flagY
+        flagWidth
+        flagHeight
+    )
+    flags := 0
+    if ev.ValueMask & xproto.ConfigWindowX != 0 { flags |= flagX }
+    if ev.ValueMask & xproto.ConfigWindowY != 0 { flags |= flagY }
+    if ev.ValueMask & xproto.ConfigWindowWidth != 0 { flags |= flagWidth }
+    if ev.ValueMask & xproto.ConfigWindowHeight != 0 { flags |= flagHeight }
+
+    s.configure(c, clientGravity(c.client), flags,
+        int(ev.X), int(ev.Y), int(ev.Width), int(ev.Height))
+}
+
+// grant an unmanaged window's ConfigureRequest as-is
+func passConfigureRequest(X *xgbutil.XUtil, ev xevent.ConfigureRequestEvent) {
+    // values go in the same order as the bits in the mask
+    fields := []struct {
+        mask    uint16
+        value   uint32
+    }{
+        {xproto.ConfigWindowX, uint32(ev.X)},
+        {xproto.ConfigWindowY, uint32(ev.Y)},
+        {xproto.ConfigWindowWidth, uint32(ev.Width)},
+        {xproto.ConfigWindowHeight, uint32(ev.Height)},
+        {xproto.ConfigWindowBorderWidth, uint32(ev.BorderWidth)},
+        {xproto.ConfigWindowSibling, uint32(ev.Sibling)},
+        {xproto.ConfigWindowStackMode, uint32(ev.StackMode)},
+    }
+    values := []uint32{}
+    for _, field := range fields {
+        if ev.ValueMask & field.mask != 0 {
+            values = append(values, field.value)
+        }
+    }
+    xproto.ConfigureWindow(X.Conn(), ev.Window, ev.ValueMask, values)
+}
+
+// the client's win_gravity, NorthWest if it didn't set one
+func clientGravity(client *xwindow.Window) uint {
+    hints, err := icccm.WmNormalHintsGet(client.X, client.Id)
+    if err != nil || hints.Flags & icccm.SizeHintPWinGravity == 0 {
+        return xproto.GravityNorthWest
+    }
+    return hints.WinGravity
+}
+
+// handle _NET_MOVERESIZE_WINDOW and _NET_REQUEST_FRAME_EXTENTS
+func (s *StubWM) handleClientMessage(X *xgbutil.XUtil, ev xevent.ClientMessageEvent) {
+    name, err := xprop.AtomName(X, ev.Type)
+    if err != nil { return }
+    data := ev.Data.Data32
+
+    switch name {
+    case "_NET_MOVERESIZE_WINDOW":
+        c, ok := s.lookup(ev.Window)
+        if !ok { return }
+        gravity := uint(data[0] & 0xff)
+        if gravity == 0 {
+            gravity = clientGravity(c.client)
+        }
+        // bits 8-11 say which of x, y, width and height are set
+        flags := int(data[0] >> 8) & 0xf
+        s.configure(c, gravity, flags,
+            int(int32(data[1])), int(int32(data[2])), int(data[3]), int(data[4]))
+
+    case "_NET_REQUEST_FRAME_EXTENTS":
+        ext := StubExtents
+        ewmh.FrameExtentsSet(X, ev.Window, &ewmh.FrameExtents{
+            Left: ext.Left, Right: ext.Right, Top: ext.Top, Bottom: ext.Bottom,
+        })
+    }
+}
+
+// Move and/or resize a client. `flags` says which of x (1), y (2),
+// width (4) and height (8) to change. Width and height are client sizes.
+// x, y is where the client would go without a frame; the frame goes where
+// it puts the `gravity` point of the frame on the same point of that client.
+// So with NorthWest gravity x, y is where the frame goes, and with Static
+// gravity it is where the client goes.
+func (s *StubWM) configure(c *stubClient, gravity uint, flags, x, y, width, height int) {
+    frame, err := c.frame.Geometry()
+    if err != nil { return }
+    client, err := c.client.Geometry()
+    if err != nil { return }
+
+    ext := StubExtents
+    cw, ch := client.Width(), client.Height()
+    if flags & 4 != 0 && width > 0 { cw = width }
+    if flags & 8 != 0 && height > 0 { ch = height }
+    fw, fh := ext.FrameSize(cw, ch)
+
+    fx, fy := frame.X(), frame.Y()
+    dx, dy := gravityShift(gravity, fw - cw, fh - ch)
+    if flags & 1 != 0 { fx = x - dx }
+    if flags & 2 != 0 { fy = y - dy }
+
+    c.frame.MoveResize(fx, fy, fw, fh)
+    c.client.MoveResize(ext.Left, ext.Top, cw, ch)
+    sendConfigureNotify(c.client, xrect.New(fx + ext.Left, fy + ext.Top, cw, ch))
+}
+
+// how far right and down of the frame's corner a client must be asked for
+// to put the frame's corner where the client would go, for frames `extra_w`
+// and `extra_h` bigger than the client (ICCCM 4.1.2.3)
+func gravityShift(gravity uint, extra_w, extra_h int) (int, int) {
+    if gravity == xproto.GravityStatic {
+        return StubExtents.Left, StubExtents.Top
+    }
+
+    dx, dy := 0, 0
+    switch gravity {
+    case xproto.GravityNorth, xproto.GravityCenter, xproto.GravitySouth:
+        dx = extra_w / 2
+    case xproto.GravityNorthEast, xproto.GravityEast, xproto.GravitySouthEast:
+        dx = extra_w
+    }
+    switch gravity {
+    case xproto.GravityWest, xproto.GravityCenter, xproto.GravityEast:
+        dy = extra_h / 2
+    case xproto.GravitySouthWest, xproto.GravitySouth, xproto.GravitySouthEast:
+        dy = extra_h
+    }
+    return dx, dy
+}
+
+// ICCCM 4.1.5: tell the client where it is in root coordinates, since a
+// move of its frame doesn't generate a real ConfigureNotify for it
+func sendConfigureNotify(client *xwindow.Window, geom xrect.Rect) {
+    ev := xproto.ConfigureNotifyEvent{
+        Event:  client.Id,
+        Window: client.Id,
+        X:      int16(geom.X()),
+        Y:      int16(geom.Y()),
+        Width:  uint16(geom.Width()),
+        Height: uint16(geom.Height()),
+    }
+    xproto.SendEvent(client.X.Conn(), false, client.Id,
+        xproto.EventMaskStructureNotify, string(ev.Bytes()))
+}
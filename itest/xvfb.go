@@ -0,0 +1,70 @@
+package itest
+
+/* xvfb.go
+   Start a private, headless X server for a test run.
+   */
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "os/exec"
+    "strings"
+    "time"
+)
+
+// how long to wait for Xvfb to start accepting connections
+var XvfbTimeout = time.Second * 5
+
+// A headless X server
+type Xvfb struct {
+    // the display to connect to, eg ":3"
+    Display string
+    cmd     *exec.Cmd
+}
+
+// Start Xvfb with a single screen of `width` x `height`. Xvfb picks a free
+// display number itself and tells us which one through -displayfd, so runs
+// don't trip over each other or over a real X server.
+func StartXvfb(width, height int) (*Xvfb, error) {
+    read, write, err := os.Pipe()
+    if err != nil { return nil, err }
+    defer read.Close()
+
+    cmd := exec.Command("Xvfb",
+        "-displayfd", "3",
+        "-screen", "0", fmt.Sprintf("%dx%dx24", width, height),
+        "-nolisten", "tcp")
+    // the first extra file is fd 3 in the child
+    cmd.ExtraFiles = []*os.File{write}
+    err = cmd.Start()
+    write.Close()
+    if err != nil {
+        return nil, fmt.Errorf("StartXvfb: couldn't run Xvfb: %v", err)
+    }
+
+    display := make(chan string, 1)
+    go func() {
+        line, _ := bufio.NewReader(read).ReadString('\n')
+        display <- strings.TrimSpace(line)
+    }()
+
+    select {
+    case num := <-display:
+        if num == "" {
+            cmd.Process.Kill()
+            cmd.Wait()
+            return nil, fmt.Errorf("StartXvfb: Xvfb exited without picking a display")
+        }
+        return &Xvfb{":" + num, cmd}, nil
+    case <-time.After(XvfbTimeout):
+        cmd.Process.Kill()
+        cmd.Wait()
+        return nil, fmt.Errorf("StartXvfb: no display after %v", XvfbTimeout)
+    }
+}
+
+// shut the server down
+func (x *Xvfb) Stop() {
+    x.cmd.Process.Kill()
+    x.cmd.Wait()
+}
@@ -0,0 +1,107 @@
+package main
+
+/*
+Keyboard versions of the cross's actions. Each one works on the focused
+window and its neighbor in the direction of the key pressed:
+
+    swap:  trade places with the neighbor
+    split: split the neighbor, taking the half closest to the focused window
+    shove: move to the far side of the neighbor
+*/
+
+import (
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/keybind"
+    "github.com/BurntSushi/xgbutil/xevent"
+    "github.com/BurntSushi/xgbutil/xwindow"
+
+    "github.com/justjake/j3/config"
+    "github.com/justjake/j3/wm"
+
+    "fmt"
+)
+
+// the order of config.DirectionKeys
+var keyDirections = []wm.Direction{wm.Top, wm.Right, wm.Bottom, wm.Left}
+
+// an action on the focused window and its neighbor in `dir`
+type keyAction func(focused, neighbor *xwindow.Window, dir wm.Direction) error
+
+func keySwap(focused, neighbor *xwindow.Window, dir wm.Direction) error {
+    return wm.Swap(neighbor, focused)
+}
+
+func keySplit(focused, neighbor *xwindow.Window, dir wm.Direction) error {
+    // the half closest to us is the one facing back the way we came
+    return wm.Split(neighbor, focused, dir.Opposite())
+}
+
+func keyShove(focused, neighbor *xwindow.Window, dir wm.Direction) error {
+    return wm.Shove(neighbor, focused, dir)
+}
+
+// a key action, and the modifiers it's bound to
+type keyBinding struct {
+    name    string
+    mods    string
+    action  keyAction
+}
+
+// the key actions, with the modifiers `conf` binds each one to
+func keyActions(conf *config.Config) []keyBinding {
+    return []keyBinding{
+        {"swap", conf.KeyModsSwap, keySwap},
+        {"split", conf.KeyModsSplit, keySplit},
+        {"shove", conf.KeyModsShove, keyShove},
+    }
+}
+
+// every key combo `conf` binds, eg "Mod4-Shift-Up"
+func keyCombos(conf *config.Config) []string {
+    combos := []string{}
+    for _, a := range keyActions(conf) {
+        if a.mods == "" { continue }
+        for _, key := range conf.DirectionKeys {
+            combos = append(combos, a.mods + "-" + key)
+        }
+    }
+    return combos
+}
+
+// Grab the key bindings in `conf` on the root window. Stops at the first
+// one that can't be grabbed, eg because the window manager already uses it.
+func BindKeyActions(X *xgbutil.XUtil, conf *config.Config) error {
+    for _, a := range keyActions(conf) {
+        if a.mods == "" { continue }
+        for i, key := range conf.DirectionKeys {
+            name, action, dir := a.name, a.action, keyDirections[i]
+            combo := a.mods + "-" + key
+            err := keybind.KeyPressFun(func(X *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+                runKeyAction(X, name, action, dir, conf.AdjacencyEpsilon)
+            }).Connect(X, X.RootWin(), combo, true)
+            if err != nil {
+                return fmt.Errorf("BindKeyActions: couldn't bind %s %v to %s: %v", name, dir, combo, err)
+            }
+        }
+    }
+    return nil
+}
+
+func runKeyAction(X *xgbutil.XUtil, name string, action keyAction, dir wm.Direction, epsilon int) {
+    focused, err := wm.ActiveWindow(X)
+    if err != nil {
+        log.Printf("KeyAction: no focused window to %s: %v\n", name, err)
+        return
+    }
+    neighbor, err := wm.FindNeighbor(focused, dir, epsilon)
+    if err != nil {
+        log.Printf("KeyAction: nothing to %s with: %v\n", name, err)
+        return
+    }
+
+    // in the background, like drops on the cross, so the event loop is
+    // free to deliver the geometry changes the action waits on
+    wm.Configures.Submit(neighbor.Id, func() error {
+        return action(focused, neighbor, dir)
+    })
+}
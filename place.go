@@ -0,0 +1,86 @@
+package main
+
+/* place.go
+   Decide which cross to show over a target window, and where, so that
+   every icon on it can actually be reached with the mouse. Also place the
+   ghost outlines that preview what an icon's action will do, and work out
+   which windows the target could be.
+   */
+import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/xrect"
+
+    "github.com/justjake/j3/plan"
+    "github.com/justjake/j3/ui"
+    "github.com/justjake/j3/util"
+    "github.com/justjake/j3/wm"
+)
+
+// the part of `target` that the user can see: whatever of it is on its
+// monitor, outside of any panels
+func visibleArea(X *xgbutil.XUtil, target xrect.Rect) (visible, usable xrect.Rect) {
+    usable = wm.HeadFor(X, target).Usable
+    visible, ok := plan.Intersect(target, usable)
+    if !ok {
+        // the target is entirely under a panel or off screen. Stay as close
+        // to it as we can
+        visible = target
+    }
+    return visible, usable
+}
+
+// Give `cross_ui` the biggest layout that fits inside the visible part of
+// `target`, and find the position to show it at: centered over the visible
+// part of the target, moved as needed to stay inside it, and then inside
+// the usable area of the monitor.
+func placeCross(X *xgbutil.XUtil, cross_ui *ui.Cross, target xrect.Rect) (x, y int, err error) {
+    visible, usable := visibleArea(X, target)
+    err = cross_ui.SetLayout(X, cross_ui.LayoutFor(visible.Width(), visible.Height()))
+    if err != nil { return 0, 0, err }
+
+    geom := cross_ui.Window.Geom
+    x, y = util.CenterOver(geom, visible)
+    x, y = util.ClampInside(geom, x, y, visible)
+    x, y = util.ClampInside(geom, x, y, usable)
+    return x, y, nil
+}
+
+// the windows that could be the target with the pointer at rx, ry, topmost
+// first: every managed window under it except the one being dragged
+func targetsAt(X *xgbutil.XUtil, rx, ry int, incoming xproto.Window) []xproto.Window {
+    under, err := wm.ManagedWindowsAt(X, rx, ry)
+    if err != nil {
+        log.Printf("targetsAt: %v\n", err)
+        return nil
+    }
+    targets := under[:0]
+    for _, win := range under {
+        if win != incoming {
+            targets = append(targets, win)
+        }
+    }
+    return targets
+}
+
+// outline where `p` will put each window, one ghost per window. Ghosts
+// the plan doesn't need are hidden.
+func showPlan(p wm.Plan, ghosts []*ui.Ghost) {
+    for i, ghost := range ghosts {
+        if i >= len(p) {
+            ghost.Hide()
+            continue
+        }
+        err := ghost.Show(p[i].Rect)
+        if err != nil {
+            log.Printf("showPlan: couldn't outline %v: %v\n", p[i].Rect, err)
+            ghost.Hide()
+        }
+    }
+}
+
+func hideGhosts(ghosts []*ui.Ghost) {
+    for _, ghost := range ghosts {
+        ghost.Hide()
+    }
+}
@@ -0,0 +1,34 @@
+package plan
+
+type Direction uint8
+const (
+    Top Direction = 1 << iota
+    Right
+    Bottom
+    Left
+)
+
+// lol a string method
+// TODO: support directions that have been masked together to form things like TopLeft, etc
+func (d Direction) String() string {
+    if d == Top { return "Top" }
+    if d == Right { return "Right" }
+    if d == Bottom { return "Bottom" }
+    if d == Left { return "Left" }
+    return "Unknown Direction"
+}
+
+func (d Direction) Opposite() Direction {
+    switch d {
+    case Top: return Bottom
+    case Bottom: return Top
+    case Left: return Right
+    case Right: return Left
+    }
+    panic("Direction.Opposite: Unreachable")
+}
+
+// true for Left and Right, whose lengths are widths
+func (d Direction) Horizontal() bool {
+    return d == Left || d == Right
+}
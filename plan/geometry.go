@@ -0,0 +1,111 @@
+package plan
+
+/* geometry.go
+   Rectangle math shared by the planners and the rest of j3.
+   */
+import (
+    "github.com/BurntSushi/xgbutil/xrect"
+)
+
+// the size of the decorations on each side of a client window
+type Extents struct {
+    Left, Right, Top, Bottom int
+}
+
+// the client size that gives a frame of `width` x `height`
+func (e Extents) ClientSize(width, height int) (int, int) {
+    return width - e.Left - e.Right, height - e.Top - e.Bottom
+}
+
+// the frame size around a client of `width` x `height`
+func (e Extents) FrameSize(width, height int) (int, int) {
+    return width + e.Left + e.Right, height + e.Top + e.Bottom
+}
+
+// the total size of the decorations along `dir`'s axis: Left + Right for
+// Left and Right, Top + Bottom for Top and Bottom
+func (e Extents) Along(dir Direction) int {
+    if dir.Horizontal() {
+        return e.Left + e.Right
+    }
+    return e.Top + e.Bottom
+}
+
+// the overlap of two rectangles. ok is false if they don't overlap at all
+func Intersect(a, b xrect.Rect) (rect xrect.Rect, ok bool) {
+    x1 := imax(a.X(), b.X())
+    y1 := imax(a.Y(), b.Y())
+    x2 := imin(a.X() + a.Width(), b.X() + b.Width())
+    y2 := imin(a.Y() + a.Height(), b.Y() + b.Height())
+    if x2 <= x1 || y2 <= y1 {
+        return nil, false
+    }
+    return xrect.New(x1, y1, x2 - x1, y2 - y1), true
+}
+
+// Shrink `rect` to the part of it inside `area`. A rect entirely outside
+// `area` is returned untouched: there's no sensible way to clip it, and
+// leaving the window where the user put it beats making it vanish.
+func ClipRect(rect, area xrect.Rect) xrect.Rect {
+    if clipped, ok := Intersect(rect, area); ok {
+        return clipped
+    }
+    return rect
+}
+
+// return the coordinate part for an edge of a rectangle
+// for the top edge, this is just rect.Y(), but for the right edge, it's
+// rect.X() + rect.Width() to get the x-offset of the right edge
+func EdgePos(rect xrect.Rect, dir Direction) int {
+    switch dir {
+        case Top:    return rect.Y()
+        case Right:  return rect.X() + rect.Width()
+        case Bottom: return rect.Y() + rect.Height()
+        case Left:   return rect.X()
+    }
+    panic("Bad direction in EdgePosition")
+}
+
+// which edge of `geom` the point (x, y) is closest to, by splitting the
+// rectangle into four triangles along its diagonals. (x, y) is relative to
+// the rectangle's top-left corner.
+func SideOfRectangle(geom xrect.Rect, x, y int) Direction {
+    // construct algebraic functions to delinate the rectangle into sections
+    // around the center point like this: [X]
+    // these are a little confusing because x11 addresses coordinates from the top-left,
+    // where traditional euclidean graphs address from the bottom-left
+    w, h := geom.Width(), geom.Height()
+    slope := float64(h) / float64(w)
+    bl_to_tr_y := int(-1.0 * slope * float64(x)) + h
+    tl_to_br_y := int(slope * float64(x))
+
+    var dir Direction
+
+    if x < w/2 {
+        // left half of the rectangle
+        switch {
+        // we must be above both lines and on the left side of the midpoint
+        case y <= tl_to_br_y: dir = Top
+
+        // we must be below both lines and on the left side of the midpoint
+        case y >= bl_to_tr_y: dir = Bottom
+
+        // we are between the two lines and on the left side
+        default: dir = Left
+        }
+    } else {
+        // right half of the rectangle
+        if y <= bl_to_tr_y {
+            // we must be above both lines and on the left side of the midpoint
+            dir = Top
+        } else if y >= tl_to_br_y {
+            // we must be below both lines and on the left side of the midpoint
+            dir = Bottom
+        } else {
+            // we are between the two lines and on the left side
+            dir = Right
+        }
+    }
+
+    return dir
+}
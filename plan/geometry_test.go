@@ -0,0 +1,81 @@
+package plan
+
+import (
+    "github.com/BurntSushi/xgbutil/xrect"
+
+    "testing"
+)
+
+func TestEdgePos(t *testing.T) {
+    tests := []struct {
+        rect    xrect.Rect
+        dir     Direction
+        want    int
+    }{
+        {xrect.New(10, 20, 30, 40), Top, 20},
+        {xrect.New(10, 20, 30, 40), Right, 40},
+        {xrect.New(10, 20, 30, 40), Bottom, 60},
+        {xrect.New(10, 20, 30, 40), Left, 10},
+        // an empty rectangle's edges are all in the same place
+        {xrect.New(5, 5, 0, 0), Top, 5},
+        {xrect.New(5, 5, 0, 0), Right, 5},
+        {xrect.New(5, 5, 0, 0), Bottom, 5},
+        {xrect.New(5, 5, 0, 0), Left, 5},
+    }
+
+    for _, test := range tests {
+        if got := EdgePos(test.rect, test.dir); got != test.want {
+            t.Errorf("EdgePos(%v, %v) = %d, want %d", test.rect, test.dir, got, test.want)
+        }
+    }
+}
+
+func TestEdgePosBadDirection(t *testing.T) {
+    defer func() {
+        if recover() == nil {
+            t.Errorf("EdgePos(Top | Left) didn't panic")
+        }
+    }()
+    EdgePos(xrect.New(0, 0, 10, 10), Top | Left)
+}
+
+func TestSideOfRectangle(t *testing.T) {
+    square := xrect.New(0, 0, 100, 100)
+    wide := xrect.New(0, 0, 200, 100)
+    tests := []struct {
+        rect    xrect.Rect
+        x, y    int
+        want    Direction
+    }{
+        // corners go to the top and bottom edges
+        {square, 0, 0, Top},
+        {square, 100, 0, Top},
+        {square, 0, 100, Bottom},
+        {square, 100, 100, Bottom},
+        // and so do points on the diagonals
+        {square, 49, 49, Top},
+        {square, 99, 99, Bottom},
+        {square, 50, 99, Bottom},
+        // the middle of each edge
+        {square, 50, 0, Top},
+        {square, 0, 50, Left},
+        {square, 100, 50, Right},
+        {square, 50, 100, Bottom},
+        // the center
+        {square, 50, 50, Top},
+        // the diagonals of a wide rectangle are shallower
+        {wide, 100, 50, Top},
+        {wide, 199, 50, Right},
+        {wide, 1, 50, Left},
+        {wide, 60, 30, Top},
+        {wide, 60, 31, Left},
+        {wide, 60, 69, Left},
+        {wide, 60, 70, Bottom},
+    }
+
+    for _, test := range tests {
+        if got := SideOfRectangle(test.rect, test.x, test.y); got != test.want {
+            t.Errorf("SideOfRectangle(%v, %d, %d) = %v, want %v", test.rect, test.x, test.y, got, test.want)
+        }
+    }
+}
@@ -0,0 +1,163 @@
+package plan
+
+/* hints.go
+   WM_NORMAL_HINTS size constraints, so layout math can pick sizes that
+   windows like terminals will actually accept instead of fighting them.
+   All sizes here are client sizes, without window manager decorations.
+   The wm package reads these from X.
+   */
+
+// The size constraints a window asks for in WM_NORMAL_HINTS.
+// A zero MaxWidth or MaxHeight means that dimension is unbounded.
+type SizeHints struct {
+    MinWidth, MinHeight     int
+    MaxWidth, MaxHeight     int
+    BaseWidth, BaseHeight   int
+    WidthInc, HeightInc     int
+}
+
+// hints for a window that will take any size at all
+func NoSizeHints() *SizeHints {
+    return &SizeHints{WidthInc: 1, HeightInc: 1}
+}
+
+// true if the window snaps to a size increment in either dimension
+func (h *SizeHints) HasIncrements() bool {
+    return h.WidthInc > 1 || h.HeightInc > 1
+}
+
+// the largest width <= `width` that the window will accept, or the minimum
+// width if `width` is too small
+func (h *SizeHints) ConstrainWidth(width int) int {
+    return constrainLength(width, h.MinWidth, h.MaxWidth, h.BaseWidth, h.WidthInc)
+}
+
+// the largest height <= `height` that the window will accept, or the minimum
+// height if `height` is too small
+func (h *SizeHints) ConstrainHeight(height int) int {
+    return constrainLength(height, h.MinHeight, h.MaxHeight, h.BaseHeight, h.HeightInc)
+}
+
+// Constrain the length of the window's dimension along `dir`: width for
+// Left and Right, height for Top and Bottom
+func (h *SizeHints) Constrain(dir Direction, length int) int {
+    if dir == Left || dir == Right {
+        return h.ConstrainWidth(length)
+    }
+    return h.ConstrainHeight(length)
+}
+
+// the smallest and largest lengths the window accepts along `dir`.
+// max is 0 when unbounded.
+func (h *SizeHints) Range(dir Direction) (min, max int) {
+    if dir == Left || dir == Right {
+        return h.MinWidth, h.MaxWidth
+    }
+    return h.MinHeight, h.MaxHeight
+}
+
+func constrainLength(length, min, max, base, inc int) int {
+    if max > 0 && length > max {
+        length = max
+    }
+    if inc > 1 && length > base {
+        // snap down to base + n * inc
+        length = base + ((length - base) / inc) * inc
+    }
+    if length < min {
+        length = min
+    }
+    if length < 1 {
+        length = 1
+    }
+    return length
+}
+
+// the resize increment along `dir`'s axis
+func (h *SizeHints) Increment(dir Direction) int {
+    if dir == Left || dir == Right {
+        return h.WidthInc
+    }
+    return h.HeightInc
+}
+
+// true if the window would take a client length of exactly `length`
+// along `dir`'s axis
+func (h *SizeHints) Accepts(dir Direction, length int) bool {
+    return length >= 1 && h.Constrain(dir, length) == length
+}
+
+// the frame length closest to `length` (but not over it, unless the minimum
+// size says so) that the window will accept along `dir`'s axis.
+// `frame` is the size of the window's decorations along that axis.
+func FitFrame(hints *SizeHints, frame int, dir Direction, length int) int {
+    return frame + hints.Constrain(dir, length - frame)
+}
+
+// Split `total` pixels between two windows along `dir`'s axis, as evenly as
+// their size hints allow, so that both land flush against each other on the
+// first try. `first_frame` and `second_frame` are the sizes of each window's
+// decorations along the axis. Returns the frame length of the first window;
+// the second gets the rest.
+//
+// When only one window snaps to size increments, it gets the nearest size it
+// accepts and the other window takes up the slack. When both do, we look for
+// the nearest split that suits both, and if there isn't one, the first
+// window wins.
+func SplitLength(total int, dir Direction, first, second *SizeHints, first_frame, second_frame int) int {
+    ideal := total / 2
+
+    // the range of splits that respects both windows' min and max sizes
+    lo, hi := 1, total - 1
+    min1, max1 := first.Range(dir)
+    min2, max2 := second.Range(dir)
+    lo = imax(lo, first_frame + min1)
+    hi = imin(hi, total - second_frame - min2)
+    if max1 > 0 { hi = imin(hi, first_frame + max1) }
+    if max2 > 0 { lo = imax(lo, total - second_frame - max2) }
+    if lo > hi {
+        // the windows can't share this space without someone overlapping.
+        // do what we can
+        return FitFrame(first, first_frame, dir, ideal)
+    }
+    ideal = iclamp(ideal, lo, hi)
+
+    fits := func(p int) bool {
+        return first.Accepts(dir, p - first_frame) && second.Accepts(dir, total - p - second_frame)
+    }
+    // snap one window to the nearest length it accepts, rounding up when
+    // we're more than half an increment short
+    snap := func(hints *SizeHints, frame, length int) int {
+        snapped := FitFrame(hints, frame, dir, length)
+        if snapped < length && length - snapped > hints.Increment(dir) / 2 {
+            snapped += hints.Increment(dir)
+        }
+        return snapped
+    }
+
+    first_snaps := first.Increment(dir) > 1
+    second_snaps := second.Increment(dir) > 1
+    switch {
+    case !first_snaps && !second_snaps:
+        return ideal
+
+    case first_snaps && !second_snaps:
+        return iclamp(snap(first, first_frame, ideal), lo, hi)
+
+    case !first_snaps && second_snaps:
+        return iclamp(total - snap(second, second_frame, total - ideal), lo, hi)
+    }
+
+    // both snap: search outwards from the ideal split. Any split that works
+    // repeats every lcm(inc1, inc2) pixels, so we needn't look further
+    limit := first.Increment(dir) * second.Increment(dir)
+    for d := 0; d <= limit; d++ {
+        if p := ideal - d; p >= lo && fits(p) { return p }
+        if p := ideal + d; p <= hi && fits(p) { return p }
+    }
+    return iclamp(snap(first, first_frame, ideal), lo, hi)
+}
+
+func imin(a, b int) int { if a < b { return a }; return b }
+func imax(a, b int) int { if a > b { return a }; return b }
+func iclamp(x, lo, hi int) int { return imax(lo, imin(x, hi)) }
@@ -0,0 +1,119 @@
+package plan
+
+import (
+    "testing"
+)
+
+func TestConstrain(t *testing.T) {
+    tests := []struct {
+        name    string
+        hints   SizeHints
+        length  int
+        want    int
+    }{
+        {"no hints", *NoSizeHints(), 123, 123},
+        {"never below 1", *NoSizeHints(), 0, 1},
+        {"increments", SizeHints{BaseWidth: 4, WidthInc: 7}, 100, 95},
+        {"on an increment", SizeHints{BaseWidth: 4, WidthInc: 7}, 95, 95},
+        {"below base", SizeHints{BaseWidth: 10, WidthInc: 7}, 8, 8},
+        {"min", SizeHints{MinWidth: 50}, 30, 50},
+        {"max", SizeHints{MaxWidth: 80}, 100, 80},
+        {"max then increments", SizeHints{MaxWidth: 80, BaseWidth: 4, WidthInc: 7}, 100, 74},
+        {"min beats increments", SizeHints{MinWidth: 50, WidthInc: 7}, 40, 50},
+    }
+
+    for _, test := range tests {
+        if got := test.hints.ConstrainWidth(test.length); got != test.want {
+            t.Errorf("%s: ConstrainWidth(%d) = %d, want %d", test.name, test.length, got, test.want)
+        }
+
+        // the same hints turned on their side
+        h := test.hints
+        tall := SizeHints{
+            MinHeight: h.MinWidth, MaxHeight: h.MaxWidth,
+            BaseHeight: h.BaseWidth, HeightInc: h.WidthInc,
+        }
+        if got := tall.Constrain(Top, test.length); got != test.want {
+            t.Errorf("%s: Constrain(Top, %d) = %d, want %d", test.name, test.length, got, test.want)
+        }
+    }
+}
+
+func TestAccepts(t *testing.T) {
+    hints := &SizeHints{MinWidth: 11, MaxWidth: 200, BaseWidth: 4, WidthInc: 7}
+    tests := []struct {
+        length  int
+        want    bool
+    }{
+        {0, false},
+        {4, false},
+        {18, true},
+        {95, true},
+        {96, false},
+        {200, true},
+        {201, false},
+    }
+
+    for _, test := range tests {
+        if got := hints.Accepts(Left, test.length); got != test.want {
+            t.Errorf("Accepts(Left, %d) = %v, want %v", test.length, got, test.want)
+        }
+    }
+}
+
+func TestFitFrame(t *testing.T) {
+    tests := []struct {
+        name    string
+        hints   SizeHints
+        frame   int
+        length  int
+        want    int
+    }{
+        {"no hints", *NoSizeHints(), 4, 104, 104},
+        {"increments", SizeHints{WidthInc: 7}, 4, 104, 102},
+        {"min", SizeHints{MinWidth: 200}, 4, 104, 204},
+        {"max", SizeHints{MaxWidth: 50}, 4, 104, 54},
+    }
+
+    for _, test := range tests {
+        if got := FitFrame(&test.hints, test.frame, Left, test.length); got != test.want {
+            t.Errorf("%s: FitFrame(%d, Left, %d) = %d, want %d", test.name, test.frame, test.length, got, test.want)
+        }
+    }
+}
+
+func TestSplitLength(t *testing.T) {
+    none := NoSizeHints()
+    tests := []struct {
+        name            string
+        total           int
+        first, second   *SizeHints
+        first_frame     int
+        second_frame    int
+        want            int
+    }{
+        {"no hints", 400, none, none, 0, 0, 200},
+        {"first min", 400, &SizeHints{MinWidth: 300}, none, 0, 0, 300},
+        {"first max", 400, &SizeHints{MaxWidth: 100}, none, 0, 0, 100},
+        {"second min", 400, none, &SizeHints{MinWidth: 300}, 0, 0, 100},
+        {"second max", 400, none, &SizeHints{MaxWidth: 100}, 0, 0, 300},
+        // 205 is 5 pixels past a column, so round up to the next one
+        {"first snaps", 410, &SizeHints{WidthInc: 7}, none, 4, 0, 207},
+        {"second snaps", 410, none, &SizeHints{WidthInc: 7}, 0, 4, 203},
+        // the nearest multiple of 35
+        {"both snap", 400, &SizeHints{WidthInc: 7}, &SizeHints{WidthInc: 5}, 0, 0, 210},
+        // an odd total can't be split into multiples of 4 and 6, so the
+        // first window wins
+        {"both snap, no fit", 401, &SizeHints{WidthInc: 4}, &SizeHints{WidthInc: 6}, 0, 0, 200},
+        // snapping up would break the second window's minimum
+        {"snap within limits", 410, &SizeHints{WidthInc: 7}, &SizeHints{MinWidth: 205}, 4, 0, 205},
+        {"too small for both", 400, &SizeHints{MinWidth: 300}, &SizeHints{MinWidth: 300}, 0, 0, 300},
+    }
+
+    for _, test := range tests {
+        got := SplitLength(test.total, Left, test.first, test.second, test.first_frame, test.second_frame)
+        if got != test.want {
+            t.Errorf("%s: SplitLength(%d) = %d, want %d", test.name, test.total, got, test.want)
+        }
+    }
+}
@@ -0,0 +1,65 @@
+package plan
+
+/* neighbor.go
+   Which window is "next to" another one, for keyboard actions that work
+   on the focused window and its neighbor in some direction.
+   */
+import (
+    "github.com/BurntSushi/xgbutil/xrect"
+)
+
+// how much the spans of `a` and `b` perpendicular to `dir` overlap, eg
+// their shared width for Top and Bottom. Negative if there's a gap
+// between them.
+func Overlap(a, b xrect.Rect, dir Direction) int {
+    if dir.Horizontal() {
+        return imin(EdgePos(a, Bottom), EdgePos(b, Bottom)) - imax(EdgePos(a, Top), EdgePos(b, Top))
+    }
+    return imin(EdgePos(a, Right), EdgePos(b, Right)) - imax(EdgePos(a, Left), EdgePos(b, Left))
+}
+
+// true if the spans of `a` and `b` perpendicular to `dir` overlap, or come
+// within `slack` pixels of touching. Windows with overlapping spans can share
+// a seam.
+//
+// TODO: consider adding a mimumum overlap
+func SpansOverlap(a, b xrect.Rect, dir Direction, slack int) bool {
+    return Overlap(a, b, dir) >= -slack
+}
+
+// how far past `from`'s `dir` edge `rect` starts. Negative if they overlap
+// along `dir`'s axis.
+func gapToward(from, rect xrect.Rect, dir Direction) int {
+    switch dir {
+    case Top, Left:
+        return EdgePos(from, dir) - EdgePos(rect, dir.Opposite())
+    }
+    return EdgePos(rect, dir.Opposite()) - EdgePos(from, dir)
+}
+
+// Find the neighbor of `from` in direction `dir` among `candidates`: the
+// closest rectangle on that side of it that's at least partly level with
+// it. Rectangles that poke up to `epsilon` pixels back over `from`'s edge
+// still count as being on that side, since neighboring windows rarely line
+// up exactly. Ties go to the rectangle that's more level with `from`, then
+// to the later one, so candidates in stacking order favor the top window.
+//
+// ok is false if nothing is on that side.
+func Neighbor(from xrect.Rect, candidates []xrect.Rect, dir Direction, epsilon int) (index int, ok bool) {
+    best_gap, best_overlap := 0, 0
+    for i, rect := range candidates {
+        gap := gapToward(from, rect, dir)
+        if gap < -epsilon { continue }
+        overlap := Overlap(from, rect, dir)
+        if overlap <= 0 { continue }
+
+        // anything inside the slop is as close as it gets
+        gap = imax(gap, 0)
+        better := !ok || gap < best_gap || (gap == best_gap && overlap >= best_overlap)
+        if better {
+            index, ok = i, true
+            best_gap, best_overlap = gap, overlap
+        }
+    }
+    return index, ok
+}
@@ -0,0 +1,163 @@
+/*
+Package plan works out where j3's window actions put windows, without
+talking to X. Planners take plain descriptions of the target and incoming
+windows and return plain rectangles, so layouts can be worked out (and
+tested) without a display. The wm package reads windows from X, runs the
+planners, and applies the results.
+*/
+package plan
+
+import (
+    "github.com/BurntSushi/xgbutil/xrect"
+
+    "fmt"
+)
+
+// Everything a planner needs to know about a window
+type Window struct {
+    // geometry including decorations, in root window coordinates
+    Frame   xrect.Rect
+    // size constraints of the client window. nil means none
+    Hints   *SizeHints
+    // decoration sizes
+    Extents Extents
+    // the usable part of the monitor the window is on
+    Area    xrect.Rect
+}
+
+// the window's size hints, never nil
+func (w *Window) hints() *SizeHints {
+    if w.Hints == nil {
+        return NoSizeHints()
+    }
+    return w.Hints
+}
+
+// the frame length closest to `length` along `dir`'s axis that the window
+// will accept
+func (w *Window) Fit(dir Direction, length int) int {
+    return FitFrame(w.hints(), w.Extents.Along(dir), dir, length)
+}
+
+// which of the two windows in an action a placement is for
+type Role int
+const (
+    Target Role = iota
+    Incoming
+)
+
+func (r Role) String() string {
+    if r == Target { return "Target" }
+    return "Incoming"
+}
+
+// Where an action puts one of its windows: the window's new frame geometry
+type Placement struct {
+    Role    Role
+    Rect    xrect.Rect
+}
+
+// Everything an action does, in the order it should happen
+type Plan []Placement
+
+// the window with `role`
+func pick(role Role, target, incoming *Window) *Window {
+    if role == Target { return target }
+    return incoming
+}
+
+// Split the target's visible area in half along `dir`'s axis. The incoming
+// window takes the half on the `dir` side, and the target keeps the rest.
+// The split point respects both windows' size hints.
+func PlanSplit(target, incoming Window, dir Direction) Plan {
+    // only split the part of the target that's on screen
+    bounds := ClipRect(target.Frame, target.Area)
+
+    // the window on the top or left, and the one on the bottom or right
+    first, second := Target, Incoming
+    if dir == Top || dir == Left {
+        first, second = Incoming, Target
+    }
+    a := pick(first, &target, &incoming)
+    b := pick(second, &target, &incoming)
+
+    if dir.Horizontal() {
+        left_width := SplitLength(bounds.Width(), Left, a.hints(), b.hints(),
+            a.Extents.Along(Left), b.Extents.Along(Left))
+        right_width := bounds.Width() - left_width
+
+        // right first, then left
+        return Plan{
+            {second, xrect.New(bounds.X() + left_width, bounds.Y(),
+                right_width, b.Fit(Top, bounds.Height()))},
+            {first, xrect.New(bounds.X(), bounds.Y(),
+                left_width, a.Fit(Top, bounds.Height()))},
+        }
+    }
+
+    top_height := SplitLength(bounds.Height(), Top, a.hints(), b.hints(),
+        a.Extents.Along(Top), b.Extents.Along(Top))
+    bottom_height := bounds.Height() - top_height
+
+    // bottom first, then top
+    return Plan{
+        {second, xrect.New(bounds.X(), bounds.Y() + top_height,
+            b.Fit(Left, bounds.Width()), bottom_height)},
+        {first, xrect.New(bounds.X(), bounds.Y(),
+            a.Fit(Left, bounds.Width()), top_height)},
+    }
+}
+
+// Trade the positions and sizes of the two windows. A window that was
+// partly off screen shouldn't drag its partner off screen with it, so only
+// the visible part of each window counts.
+func PlanSwap(target, incoming Window) Plan {
+    target_bounds := ClipRect(target.Frame, target.Area)
+    incoming_bounds := ClipRect(incoming.Frame, incoming.Area)
+    return Plan{
+        {Target, incoming_bounds},
+        {Incoming, target_bounds},
+    }
+}
+
+// Put the incoming window on the `dir` side of the target, stretched to
+// match the target along the other axis.
+//
+// The result is clipped to the target's usable area. If the incoming
+// window doesn't fit between the target and the edge of the monitor, it
+// shrinks to the space available instead of sliding off screen. If there's
+// no space at all, that's an error.
+func PlanShove(target, incoming Window, dir Direction) (Plan, error) {
+    t, i := target.Frame, incoming.Frame
+
+    // where the incoming window would go on an infinite screen
+    var want xrect.Rect
+    switch dir {
+    case Top:    want = xrect.New(t.X(), t.Y() - i.Height(), t.Width(), i.Height())
+    case Bottom: want = xrect.New(t.X(), t.Y() + t.Height(), t.Width(), i.Height())
+    case Left:   want = xrect.New(t.X() - i.Width(), t.Y(), i.Width(), t.Height())
+    case Right:  want = xrect.New(t.X() + t.Width(), t.Y(), i.Width(), t.Height())
+    default:
+        return nil, fmt.Errorf("PlanShove: bad direction %v", dir)
+    }
+
+    got, ok := Intersect(want, target.Area)
+    if !ok {
+        return nil, fmt.Errorf("PlanShove: no room on the %v side of the target", dir)
+    }
+
+    // the incoming window has to stretch or shrink to fit, so ask for the
+    // closest size its size hints allow
+    width := incoming.Fit(Left, got.Width())
+    height := incoming.Fit(Top, got.Height())
+
+    // keep the incoming window flush against the target. Any slack left over
+    // by the size hints goes on the side away from the target
+    x, y := got.X(), got.Y()
+    switch dir {
+    case Top:  y = t.Y() - height
+    case Left: x = t.X() - width
+    }
+
+    return Plan{{Incoming, xrect.New(x, y, width, height)}}, nil
+}
@@ -0,0 +1,178 @@
+package plan
+
+import (
+    "github.com/BurntSushi/xgbutil/xrect"
+
+    "testing"
+)
+
+// the whole screen, as the usable area for every window here
+var screen = xrect.New(0, 0, 1000, 800)
+
+func sameRect(a, b xrect.Rect) bool {
+    return a.X() == b.X() && a.Y() == b.Y() && a.Width() == b.Width() && a.Height() == b.Height()
+}
+
+func checkPlan(t *testing.T, name string, got, want Plan) {
+    if len(got) != len(want) {
+        t.Errorf("%s: got %d placements %v, want %v", name, len(got), got, want)
+        return
+    }
+    for i := range want {
+        if got[i].Role != want[i].Role || !sameRect(got[i].Rect, want[i].Rect) {
+            t.Errorf("%s: placement %d is %v %v, want %v %v", name, i,
+                got[i].Role, got[i].Rect, want[i].Role, want[i].Rect)
+        }
+    }
+}
+
+func TestPlanSplit(t *testing.T) {
+    plain := Window{Frame: xrect.New(100, 100, 400, 300), Area: screen}
+    // hanging off the right edge of the screen
+    offscreen := Window{Frame: xrect.New(800, 100, 400, 300), Area: screen}
+    // a 410 pixel wide target that's a terminal with 7 pixel columns and a
+    // 2 pixel border on each side
+    terminal := Window{
+        Frame:   xrect.New(100, 100, 410, 300),
+        Hints:   &SizeHints{WidthInc: 7, HeightInc: 1},
+        Extents: Extents{Left: 2, Right: 2},
+        Area:    screen,
+    }
+    incoming := Window{Frame: xrect.New(0, 0, 50, 50), Area: screen}
+
+    tests := []struct {
+        name    string
+        target  Window
+        dir     Direction
+        want    Plan
+    }{
+        {"Right", plain, Right, Plan{
+            {Incoming, xrect.New(300, 100, 200, 300)},
+            {Target, xrect.New(100, 100, 200, 300)},
+        }},
+        {"Left", plain, Left, Plan{
+            {Target, xrect.New(300, 100, 200, 300)},
+            {Incoming, xrect.New(100, 100, 200, 300)},
+        }},
+        {"Bottom", plain, Bottom, Plan{
+            {Incoming, xrect.New(100, 250, 400, 150)},
+            {Target, xrect.New(100, 100, 400, 150)},
+        }},
+        {"Top", plain, Top, Plan{
+            {Target, xrect.New(100, 250, 400, 150)},
+            {Incoming, xrect.New(100, 100, 400, 150)},
+        }},
+        // only the 200 pixels on screen are split
+        {"clipped", offscreen, Right, Plan{
+            {Incoming, xrect.New(900, 100, 100, 300)},
+            {Target, xrect.New(800, 100, 100, 300)},
+        }},
+        // 205 would give the terminal 201 pixels of client, between 28 and
+        // 29 columns. 29 is closer
+        {"increments", terminal, Right, Plan{
+            {Incoming, xrect.New(307, 100, 203, 300)},
+            {Target, xrect.New(100, 100, 207, 300)},
+        }},
+    }
+
+    for _, test := range tests {
+        checkPlan(t, test.name, PlanSplit(test.target, incoming, test.dir), test.want)
+    }
+}
+
+func TestPlanSwap(t *testing.T) {
+    tests := []struct {
+        name        string
+        target      Window
+        incoming    Window
+        want        Plan
+    }{
+        {"on screen",
+            Window{Frame: xrect.New(0, 0, 500, 800), Area: screen},
+            Window{Frame: xrect.New(500, 0, 500, 400), Area: screen},
+            Plan{
+                {Target, xrect.New(500, 0, 500, 400)},
+                {Incoming, xrect.New(0, 0, 500, 800)},
+            }},
+        // the incoming window's off screen part doesn't come along
+        {"clipped",
+            Window{Frame: xrect.New(0, 0, 500, 800), Area: screen},
+            Window{Frame: xrect.New(900, -100, 300, 400), Area: screen},
+            Plan{
+                {Target, xrect.New(900, 0, 100, 300)},
+                {Incoming, xrect.New(0, 0, 500, 800)},
+            }},
+    }
+
+    for _, test := range tests {
+        checkPlan(t, test.name, PlanSwap(test.target, test.incoming), test.want)
+    }
+}
+
+func TestPlanShove(t *testing.T) {
+    target := Window{Frame: xrect.New(300, 300, 200, 100), Area: screen}
+    incoming := Window{Frame: xrect.New(0, 0, 150, 120), Area: screen}
+    // a window that only comes in sizes that are multiples of 10
+    chunky := Window{
+        Frame: xrect.New(0, 0, 150, 125),
+        Hints: &SizeHints{WidthInc: 10, HeightInc: 10},
+        Area:  screen,
+    }
+
+    tests := []struct {
+        name        string
+        target      Window
+        incoming    Window
+        dir         Direction
+        want        xrect.Rect
+    }{
+        {"Top", target, incoming, Top, xrect.New(300, 180, 200, 120)},
+        {"Bottom", target, incoming, Bottom, xrect.New(300, 400, 200, 120)},
+        {"Left", target, incoming, Left, xrect.New(150, 300, 150, 100)},
+        {"Right", target, incoming, Right, xrect.New(500, 300, 150, 100)},
+        // only 50 pixels between the target and the edge of the screen
+        {"clipped Left",
+            Window{Frame: xrect.New(50, 300, 200, 100), Area: screen},
+            incoming, Left, xrect.New(0, 300, 50, 100)},
+        {"clipped Bottom",
+            Window{Frame: xrect.New(300, 700, 200, 60), Area: screen},
+            incoming, Bottom, xrect.New(300, 760, 200, 40)},
+        // 125 snaps down to 120, and the window stays against the target
+        {"increments Top", target, chunky, Top, xrect.New(300, 180, 200, 120)},
+        {"increments Bottom",
+            Window{Frame: xrect.New(300, 300, 205, 100), Area: screen},
+            chunky, Bottom, xrect.New(300, 400, 200, 120)},
+    }
+
+    for _, test := range tests {
+        got, err := PlanShove(test.target, test.incoming, test.dir)
+        if err != nil {
+            t.Errorf("%s: %v", test.name, err)
+            continue
+        }
+        checkPlan(t, test.name, got, Plan{{Incoming, test.want}})
+    }
+}
+
+func TestPlanShoveNoRoom(t *testing.T) {
+    incoming := Window{Frame: xrect.New(0, 0, 150, 120), Area: screen}
+    tests := []struct {
+        name    string
+        target  xrect.Rect
+        dir     Direction
+    }{
+        {"Left", xrect.New(0, 300, 200, 100), Left},
+        {"Top", xrect.New(300, 0, 200, 100), Top},
+        {"Right", xrect.New(800, 300, 200, 100), Right},
+        {"Bottom", xrect.New(300, 700, 200, 100), Bottom},
+        {"bad direction", xrect.New(300, 300, 200, 100), Top | Left},
+    }
+
+    for _, test := range tests {
+        target := Window{Frame: test.target, Area: screen}
+        got, err := PlanShove(target, incoming, test.dir)
+        if err == nil {
+            t.Errorf("%s: got %v, want an error", test.name, got)
+        }
+    }
+}
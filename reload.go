@@ -0,0 +1,73 @@
+package main
+
+/*
+Watch for reasons to reload the config file: a SIGHUP, or the file
+itself changing on disk.
+*/
+
+import (
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
+)
+
+// how often to check the config file for changes
+const ConfigPollInterval = time.Second * 2
+
+// the parts of a file we look at to decide if it changed
+type fileStamp struct {
+    exists  bool
+    size    int64
+    modTime time.Time
+}
+
+func stampFile(path string) fileStamp {
+    info, err := os.Stat(path)
+    if err != nil {
+        return fileStamp{}
+    }
+    return fileStamp{true, info.Size(), info.ModTime()}
+}
+
+// Returns a channel that receives a value whenever the config at `path`
+// should be reloaded: on SIGHUP, or when the file is created, removed or
+// modified. We stat the file every `interval` rather than using inotify,
+// which keeps j3 free of platform-specific code.
+//
+// Reload requests that arrive while one is already pending are merged, so a
+// burst of saves from an editor only causes one reload.
+func WatchConfig(path string, interval time.Duration) <-chan struct{} {
+    reload := make(chan struct{}, 1)
+    request := func() {
+        select {
+        case reload <- struct{}{}:
+        default:
+            // a reload is already pending
+        }
+    }
+
+    hup := make(chan os.Signal, 1)
+    signal.Notify(hup, syscall.SIGHUP)
+
+    go func() {
+        last := stampFile(path)
+        ticker := time.NewTicker(interval)
+        for {
+            select {
+            case <-hup:
+                log.Printf("WatchConfig: got SIGHUP, reloading %s\n", path)
+                request()
+            case <-ticker.C:
+                now := stampFile(path)
+                if now != last {
+                    log.Printf("WatchConfig: %s changed on disk, reloading\n", path)
+                    last = now
+                    request()
+                }
+            }
+        }
+    }()
+
+    return reload
+}
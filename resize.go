@@ -11,13 +11,14 @@ import (
     "github.com/BurntSushi/xgbutil/xrect"
     "github.com/BurntSushi/xgbutil/xwindow"
     "github.com/BurntSushi/xgbutil/mousebind"
-    "github.com/BurntSushi/xgbutil/ewmh"
     "github.com/BurntSushi/xgbutil/xevent"
 
+    "github.com/justjake/j3/config"
+    "github.com/justjake/j3/plan"
     "github.com/justjake/j3/wm"
     "github.com/justjake/j3/ui" // temporary, for bug hunting
+    "github.com/justjake/j3/util"
 
-    "container/list"
     "fmt"
     "time"
 )
@@ -35,14 +36,31 @@ func ResizeDirection(X *xgbutil.XUtil, win *xwindow.Window, dir wm.Direction, px
         return nil
     }
 
-    geom, err := win.Geometry()
+    // snapshot both geometries up front. With the wm geometry tracker
+    // running these come from its cache, which PollFor compares against
+    pre_decor, geom, err := wm.Geometries(win)
     if err != nil {
-        return fmt.Errorf("Resize: coudn't get normal geometry: %v", err)
+        return fmt.Errorf("Resize: coudn't get geometry: %v", err)
     }
-    w, h := geom.Width(), geom.Height()
     log.Printf("ResizeDirection: pre_geom == %v\n", geom)
 
+    // if the window manager can move and resize in one request, work out
+    // where the frame ends up with the opposite edge held still, and go
+    // straight there, with a gravity that keeps that edge still even if
+    // size hints round the new size
+    if wm.Supported(X, "_NET_MOVERESIZE_WINDOW") {
+        x, y := pre_decor.X(), pre_decor.Y()
+        w, h := pre_decor.Width(), pre_decor.Height()
+        switch dir {
+        case wm.Left:   x, w = x - px, w + px
+        case wm.Right:  w = w + px
+        case wm.Top:    y, h = y - px, h + px
+        case wm.Bottom: h = h + px
+        }
+        return wm.MoveResize(win, x, y, w, h, wm.ResizeGravity(dir))
+    }
 
+    w, h := geom.Width(), geom.Height()
     if dir == wm.Left || dir == wm.Right {
         // horizontal resize
         w += px
@@ -50,13 +68,7 @@ func ResizeDirection(X *xgbutil.XUtil, win *xwindow.Window, dir wm.Direction, px
         h += px
     }
 
-    // two-step resize -> move process, to compensate for WM peculiarities and window sizing hints
-    // first save the initial position info
-    pre_decor, err := win.DecorGeometry()
-    if err != nil {
-        return fmt.Errorf("Resize: coudn't get decorated geometry: %v", err)
-    }
-
+    // otherwise, two-step resize -> move process, to compensate for WM peculiarities and window sizing hints
 
     // resize the window
     err = win.WMResize(w, h)
@@ -77,8 +89,8 @@ func ResizeDirection(X *xgbutil.XUtil, win *xwindow.Window, dir wm.Direction, px
 
     // the opposite edge should stay in the same place
     op := dir.Opposite()
-    pre_edge := EdgePos(pre_decor, op)
-    post_edge := EdgePos(post_decor, op)
+    pre_edge := plan.EdgePos(pre_decor, op)
+    post_edge := plan.EdgePos(post_decor, op)
     delta := post_edge - pre_edge
 
     x, y := post_decor.X(), post_decor.Y()
@@ -97,69 +109,14 @@ func ResizeDirection(X *xgbutil.XUtil, win *xwindow.Window, dir wm.Direction, px
     return wm.Move(win, x, y)
 }
 
-func SideOfRectangle(geom xrect.Rect, x, y int) wm.Direction {
-    // construct algebraic functions to delinate the rectangle into sections
-    // around the center point like this: [X]
-    // these are a little confusing because x11 addresses coordinates from the top-left,
-    // where traditional euclidean graphs address from the bottom-left
-    w, h := geom.Width(), geom.Height()
-    slope := float64(h) / float64(w)
-    bl_to_tr_y := int(-1.0 * slope * float64(x)) + h
-    tl_to_br_y := int(slope * float64(x))
-
-    var dir wm.Direction
-
-    if x < w/2 {
-        // left half of the rectangle
-        switch {
-        // we must be above both lines and on the left side of the midpoint
-        case y <= tl_to_br_y: dir = wm.Top
-
-        // we must be below both lines and on the left side of the midpoint
-        case y >= bl_to_tr_y: dir = wm.Bottom
-
-        // we are between the two lines and on the left side
-        default: dir = wm.Left
-        }
-    } else {
-        // right half of the rectangle
-        if y <= bl_to_tr_y {
-            // we must be above both lines and on the left side of the midpoint
-            dir = wm.Top
-        } else if y >= tl_to_br_y {
-            // we must be below both lines and on the left side of the midpoint
-            dir = wm.Bottom
-        } else {
-            // we are between the two lines and on the left side
-            dir = wm.Right
-        }
-    }
-
-    return dir
-}
-
-// return the coordinate part for an edge of a rectangle
-// for the top edge, this is just rect.Y(), but for the right edge, it's
-// rect.X() + rect.Width() to get the x-offset of the right edge
-func EdgePos(rect xrect.Rect, dir wm.Direction) int {
-    switch dir {
-        case wm.Top:    return rect.Y()
-        case wm.Right:  return rect.X() + rect.Width()
-        case wm.Bottom: return rect.Y() + rect.Height()
-        case wm.Left:   return rect.X()
-    }
-    log.Panic("Bad direction in EdgePosition")
-    return 0
-}
-
 // move the incoming window so that it is directly adjacent to the target's edge
 func AdjoinEdge(target, incoming *xwindow.Window, dir wm.Direction) error {
-    t, err := target.DecorGeometry()
+    t, err := wm.FrameGeometry(target)
     if err != nil { return err }
-    i, err := incoming.DecorGeometry()
+    i, err := wm.FrameGeometry(incoming)
     if err != nil { return err }
 
-    delta := EdgePos(t, dir) - EdgePos(i, dir.Opposite())
+    delta := plan.EdgePos(t, dir) - plan.EdgePos(i, dir.Opposite())
 
     if dir == wm.Left || dir == wm.Right {
         return wm.Move(incoming, delta + i.X(), i.Y())
@@ -178,18 +135,52 @@ func abs(x int) int {
 }
 
 type ResizeDrag struct {
-    Window      *xwindow.Window
-    Direction   wm.Direction
-    Adjacent    *list.List //[]*xwindow.Window  // the windows to resize in the opposite direction
-    LastX       int            // original mouse down position
-    LastY       int
+    Window      *xwindow.Window // the window that was clicked
+    Direction   wm.Direction   // which of its edges is being dragged
+    StartX      int            // original mouse down position
+    StartY      int
+
+    // Built by the first configure job for Window, so it sees the geometry
+    // left by any resize still in flight from the last drag. Only touch
+    // these from jobs on that window's queue.
+    Seam        *Seam          // the seam under the dragged edge, and every window along it
+    StartPos    int            // original seam position
+    seamErr     error
+}
+
+// how far the mouse has moved along the seam's axis since the drag started
+func (drag *ResizeDrag) delta(rx, ry int) int {
+    if drag.Direction == wm.Top || drag.Direction == wm.Bottom {
+        return ry - drag.StartY
+    }
+    return rx - drag.StartX
+}
+
+// the drag's seam, gathering every window along the dragged edge, on both
+// sides, the first time it's needed
+func (drag *ResizeDrag) seam(epsilon int) (*Seam, error) {
+    if drag.Seam == nil && drag.seamErr == nil {
+        drag.Seam, drag.seamErr = NewSeam(drag.Window.X, drag.Window, drag.Direction, epsilon)
+        if drag.seamErr == nil {
+            drag.StartPos = drag.Seam.Position
+        }
+    }
+    return drag.Seam, drag.seamErr
 }
 
 
-func ManageResizingWindows(X *xgbutil.XUtil) {
+// Bind resizing to conf.KeyComboResize on the root window. Call Destroy on
+// the returned drag to unbind it.
+func ManageResizingWindows(X *xgbutil.XUtil, conf *config.Config) (*util.Drag, error) {
 
     var DRAG_DATA *ResizeDrag
 
+    // the seam is only good for one drag
+    done := func(s *util.DragSession, from util.DragState) {
+        DRAG_DATA = nil
+    }
+    session := util.NewDragSession(util.DragHooks{Cancelled: done, Committed: done})
+
     handleDragStart := func(X *xgbutil.XUtil, rx, ry, ex, ey int) (cont bool, cursor xproto.Cursor) {
         // get the clicked window
         win, err := wm.FindManagedWindowUnderMouse(X)
@@ -206,7 +197,7 @@ func ManageResizingWindows(X *xgbutil.XUtil) {
 
         // create an xwindow.Window so we can get a rectangle to find our bearings from
         xwin := xwindow.New(X, win)
-        geom, err := xwin.DecorGeometry()
+        geom, err := wm.FrameGeometry(xwin)
         if err != nil {
             log.Printf("ResizeStart: geometry error: %v\n", err)
             return false, 0
@@ -214,228 +205,149 @@ func ManageResizingWindows(X *xgbutil.XUtil) {
 
         // get what side of the rect our mouseclick was on
         x, y := int(reply.WinX), int(reply.WinY)
-        dir := SideOfRectangle(geom, x, y)
-
-        // get coordinate part for the edge. this is either X or Y.
-        target_edge := EdgePos(geom, dir)
-
-        log.Printf("ResizeStart: on window %v - %v. Direction/edge: %v/%v\n", win, geom, dir, target_edge)
+        dir := plan.SideOfRectangle(geom, x, y)
 
-        // find adjacent windows
-        adjacent := list.New()
+        log.Printf("ResizeStart: on window %v - %v. Direction/edge: %v/%v\n", win, geom, dir, plan.EdgePos(geom, dir))
 
-        // note that this is an intellegent request: the WM only gives us a list of visible, normal windows
-        // we don't have to worry about moving hidden windows or something
-        managed_windows, err := ewmh.ClientListGet(X)
+        err = session.Arm(win)
         if err != nil {
-            // we can safley ignore this error, because then we just fall back to resizing only this window
-            log.Printf("ResizeStart: error getting EWMH client list: %v\n", err)
-        } else {
-            // select managed windows
-            // always enough space
-            // TODO: don't grossly overallocate
-            for _, candidate_id := range managed_windows {
-                // no need to run calculations for ourself!
-                if candidate_id == win { continue }
-
-                cand_window := xwindow.New(X, candidate_id)
-                cand_geom, err := cand_window.DecorGeometry()
-                if err != nil {
-                    log.Printf("ResizeStart: couldn't get geometry for ajacency candidate %v: %v\n", candidate_id, err)
-                    continue
-                }
-
-                cand_edge := EdgePos(cand_geom, dir.Opposite())
-                if abs(cand_edge - target_edge) <= AdjacencyEpsilon {
-                    // cool, edges are touching.
-                    // make sure this window isn't totally above or below the candidate
-                    // we do so by constructing a rect using the clicked window's edge
-                    // and the candidate's orthagonal dimension
-                    // if the rect overlaps, then this window is truly adjacent
-                    //
-                    // TODO: consider adding a mimumum overlap
-                    if dir == wm.Top || dir == wm.Bottom {
-                        // measuring X coords
-                        if EdgePos(cand_geom, wm.Right) < EdgePos(geom, wm.Left) { continue }
-                        if EdgePos(cand_geom, wm.Left) > EdgePos(geom, wm.Right) { continue }
-                    } else {
-                        if EdgePos(cand_geom, wm.Bottom) < EdgePos(geom, wm.Top) { continue }
-                        if EdgePos(cand_geom, wm.Top) > EdgePos(geom, wm.Bottom) { continue }
-                    }
-                    // if a window has made it to here, it is adgacent!
-                    // add it to the list
-                    log.Printf("ResizeStart: will resize adjacent window: %v - %v\n", candidate_id, cand_geom)
-                    adjacent.PushBack(cand_window)
-                }
-            }
+            log.Printf("ResizeStart: %v\n", err)
+            return false, 0
         }
-
-        // construct the drag data
-        data := ResizeDrag{xwin, dir, adjacent, rx, ry}
-
-        DRAG_DATA = &data
-
-        // TODO: finish this
-        // create an edge
-        // find the adjacent windows
-        // start the drag
+        // the seam gets built by the first resize job, once any resize
+        // still in flight from the last drag has landed. Waiting for that
+        // here would stall the event loop, which is what delivers the
+        // geometry changes the in-flight job is waiting on.
+        DRAG_DATA = &ResizeDrag{Window: xwin, Direction: dir, StartX: rx, StartY: ry}
         return true, 0
     }
 
+    // seam moves go through the wm's per-window configure queue, keyed by
+    // the window that was clicked. Motion events that arrive while the window
+    // manager is still busy merge into one move to the newest position, so
+    // dynamic resizing can't race the window manager.
     handleResize := func(rx, ry int) {
-        delta := rx - DRAG_DATA.LastX
-        if DRAG_DATA.Direction == wm.Top || DRAG_DATA.Direction == wm.Bottom {
-            delta = ry - DRAG_DATA.LastY
-        }
-
-        if DRAG_DATA.Direction == wm.Left || DRAG_DATA.Direction == wm.Top {
-            delta = delta * -1
-        }
-
-        target_geom, err := DRAG_DATA.Window.DecorGeometry()
-        if err != nil {
-            log.Printf("Geom retrieve err: %v\n", err)
-            return
-        }
-        target_edge := EdgePos(target_geom, DRAG_DATA.Direction)
-
-        // resize the target by the delta
-        err = ResizeDirection(X, DRAG_DATA.Window, DRAG_DATA.Direction, delta)
-        if err != nil {
-            log.Printf("ResizeStep: can't resize target: %v\n", err)
-            return
-        }
-
-        // calculate actual delta that occured, for resizing the adjacent windows
-        // handles issues with window sizing hints on windows like terminals
-        // making big differences for us
-        target_geom_a, err := DRAG_DATA.Window.DecorGeometry()
-        if err != nil {
-            log.Printf("ResizeStep: Geom retrieve err: %v\n", err)
-            return
-        }
-        target_edge_a := EdgePos(target_geom_a, DRAG_DATA.Direction)
-        delta = target_edge_a - target_edge
-        if DRAG_DATA.Direction == wm.Left || DRAG_DATA.Direction == wm.Top {
-            delta = delta * -1
-        }
-
-        
-        // resize each adjacent window by the opposite
-        for e := DRAG_DATA.Adjacent.Front(); e != nil; e = e.Next() {
-            // extract window from the linked list
-            adj_win := e.Value.(*xwindow.Window)
-            adj_geom, err := adj_win.DecorGeometry()
+        drag := DRAG_DATA
+        delta := drag.delta(rx, ry)
+        wm.Configures.Submit(drag.Window.Id, func() error {
+            seam, err := drag.seam(conf.AdjacencyEpsilon)
             if err != nil {
-                log.Printf("ResizeStep: can't query adjacent window %v geometry: %v", adj_win, err)
+                return fmt.Errorf("couldn't build seam: %v", err)
             }
-
-            log.Printf("ResizeStep: resizing adjacent window %v - %v: edge/delta %v/%v\n", adj_win.Id, adj_geom, DRAG_DATA.Direction.Opposite(), -delta)
-            // resize in the opposite direction, with the opposite delta
-            // except the delta should be some actual delta calculated from our source window,
-            // because issues with terminal windows happen
-            err = ResizeDirection(X, adj_win, DRAG_DATA.Direction.Opposite(), -delta)
-            // then to garuntee the edges touch...
-            AdjoinEdge(DRAG_DATA.Window, adj_win, DRAG_DATA.Direction)
-
-
-            if err != nil {
-                log.Printf("ResizeStep: can't resize adjacent window %v: %v\n", adj_win, err)
-                continue
-            }
-        }
-
-        // save new coordinates
-        DRAG_DATA.LastX = rx
-        DRAG_DATA.LastY = ry
+            return seam.MoveTo(drag.StartPos + delta)
+        })
     }
 
     handleDragStep := func(X *xgbutil.XUtil, rx, ry, ex, ey int) {
-        if DynamicDragResize {
+        // resizes never have a target to drop on
+        if session.State() == util.DragArmed {
+            session.Leave()
+        }
+        if conf.DynamicDragResize {
             handleResize(rx, ry)
         }
     }
 
     handleDragEnd := func(X *xgbutil.XUtil, rx, ry, ex, ey int) {
         // only run on high enough deltas. Prevents windows from resizing when the user has gone "nah."
-        // use the adjacency epsilon here too
-        delta := abs(rx - DRAG_DATA.LastX)
-        if DRAG_DATA.Direction == wm.Top || DRAG_DATA.Direction == wm.Bottom {
-            delta = abs(ry - DRAG_DATA.LastY)
-        }
-
-        if delta > AdjacencyEpsilon {
+        // use the adjacency epsilon here too.
+        // Dynamic resizes have already moved the seam if the pointer moved
+        // at all, so they always need to land on the final position, even
+        // if that is back where we started
+        delta := abs(DRAG_DATA.delta(rx, ry))
+        moved := session.State() != util.DragArmed
+        var err error
+        if (conf.DynamicDragResize && moved) || delta > conf.AdjacencyEpsilon {
             handleResize(rx, ry)
+            err = session.Commit()
         } else {
-            log.Printf("ResizeEnd: delta %v less than epsilon %v, skipping resize\n", delta, AdjacencyEpsilon)
+            log.Printf("ResizeEnd: delta %v less than epsilon %v, skipping resize\n", delta, conf.AdjacencyEpsilon)
+            err = session.Cancel()
         }
+        if err != nil {
+            log.Printf("ResizeEnd: %v\n", err)
+        }
+    }
 
-
-        DRAG_DATA = nil
+    // put the seam back where it started, if dynamic resizing moved it
+    handleDragCancel := func(X *xgbutil.XUtil) {
+        if conf.DynamicDragResize && session.State() != util.DragArmed {
+            handleResize(DRAG_DATA.StartX, DRAG_DATA.StartY)
+        }
+        err := session.Cancel()
+        if err != nil {
+            log.Printf("ResizeCancel: %v\n", err)
+        }
     }
 
-    // resizes the window by 1px vertically, then observes the actual change
+    // resizes the window by 1px vertically, then observes the actual change,
+    // in the background
     resizeBugHunt := func(X* xgbutil.XUtil, ev xevent.ButtonPressEvent) {
         // get xwindow from click
         clicked, err := wm.FindManagedWindowUnderMouse(X)
         if err != nil { log.Println(err); return }
         win := xwindow.New(X, clicked)
 
-        names := []string{"PreDecor", "PostDecorPreMove", "PostDecor", "Pre", "Post"}
-        geometries := make(map[string]xrect.Rect, 4)
+        // the waits below need the event loop to deliver ConfigureNotify
+        // events, so they can't happen on it
+        wm.Configures.Submit(clicked, func() error {
+            names := []string{"PreDecor", "PostDecorPreMove", "PostDecor", "Pre", "Post"}
+            geometries := make(map[string]xrect.Rect, 4)
 
         
-        // take measurements
-        pre_decor, err := win.DecorGeometry()
-        if err != nil {
-            log.Printf("Error fetching pre DecorGeom: %v\n", err)
-        }
-        geometries["PreDecor"] = pre_decor
+            // take measurements
+            pre_decor, err := wm.FrameGeometry(win)
+            if err != nil {
+                log.Printf("Error fetching pre DecorGeom: %v\n", err)
+            }
+            geometries["PreDecor"] = pre_decor
 
-        geo, err := win.Geometry()
-        if err != nil {
-            log.Printf("Error fetching pre Geom: %v\n", err)
-        }
-        geometries["Pre"] = geo
+            geo, err := win.Geometry()
+            if err != nil {
+                log.Printf("Error fetching pre Geom: %v\n", err)
+            }
+            geometries["Pre"] = geo
 
-        // resize vertically by 1px
-        log.Println("Resizing using window.Geometry() + 50, not DecorGeometry() + 1")
-        //err = win.WMResize(geo.Width() + 50, geo.Height())
-        if err != nil {
-            log.Println(err)
-        }
-        // wait to finish
-        err = wm.PollFor(win, wm.GeometryDiffers(geo), wm.DecorDiffers(pre_decor))
-        if err != nil {
-            log.Printf("Oops wjile waiting for resizing and things: %v\n", err)
-        }
-        post_decor_pre_move, _, err := wm.Geometries(win)
-        if err != nil {
-            log.Println(err)
-            post_decor_pre_move = pre_decor
-        }
-        geometries["PostDecorPreMove"] = post_decor_pre_move
-        // move zero pixels, then wait
-        err = wm.Move(win, post_decor_pre_move.X(), post_decor_pre_move.Y())
-        if err != nil {
-            log.Printf("error in wm.Move zero px: %v\n", err)
-        }
+            // resize vertically by 1px
+            log.Println("Resizing using window.Geometry() + 50, not DecorGeometry() + 1")
+            //err = win.WMResize(geo.Width() + 50, geo.Height())
+            if err != nil {
+                log.Println(err)
+            }
+            // wait to finish
+            err = wm.PollFor(win, wm.GeometryDiffers(geo), wm.DecorDiffers(pre_decor))
+            if err != nil {
+                log.Printf("Oops wjile waiting for resizing and things: %v\n", err)
+            }
+            post_decor_pre_move, _, err := wm.Geometries(win)
+            if err != nil {
+                log.Println(err)
+                post_decor_pre_move = pre_decor
+            }
+            geometries["PostDecorPreMove"] = post_decor_pre_move
+            // move zero pixels, then wait
+            err = wm.Move(win, post_decor_pre_move.X(), post_decor_pre_move.Y())
+            if err != nil {
+                log.Printf("error in wm.Move zero px: %v\n", err)
+            }
 
-        geo, err = win.DecorGeometry()
-        if err != nil {
-            log.Printf("Error fetching post DecorGeom: %v\n", err)
-        }
-        geometries["PostDecor"] = geo
+            geo, err = wm.FrameGeometry(win)
+            if err != nil {
+                log.Printf("Error fetching post DecorGeom: %v\n", err)
+            }
+            geometries["PostDecor"] = geo
 
-        geo, err = win.Geometry()
-        if err != nil {
-            log.Printf("Error fetching post Geom: %v\n", err)
-        }
-        geometries["Post"] = geo
+            geo, err = win.Geometry()
+            if err != nil {
+                log.Printf("Error fetching post Geom: %v\n", err)
+            }
+            geometries["Post"] = geo
 
-        for _, k := range names {
-            log.Printf("%s: %v\n", k, geometries[k])
-        }
+            for _, k := range names {
+                log.Printf("%s: %v\n", k, geometries[k])
+            }
+            return nil
+        })
 
         // release X events
         // needed if the event binding is synchronous
@@ -445,12 +357,13 @@ func ManageResizingWindows(X *xgbutil.XUtil) {
 
 
 
-    // bind handler
-    mousebind.Drag(X, X.RootWin(), X.RootWin(), KeyComboResize, true, 
-        handleDragStart, 
-        handleDragStep, 
-        handleDragEnd)
-
     mousebind.ButtonPressFun(resizeBugHunt).Connect(X, X.RootWin(), ui.KeyOption+"-Shift-Control-1", false, true)
 
+    // bind handler
+    return util.BindDrag(X, X.RootWin(), conf.KeyComboResize,
+        handleDragStart,
+        handleDragStep,
+        handleDragEnd,
+        nil,
+        handleDragCancel)
 }
@@ -0,0 +1,347 @@
+package main
+
+/*
+Seams: a line shared by the edges of several windows, on both sides of it.
+Moving a seam resizes every window along it at once, so that the windows on
+both sides stay flush with each other.
+
+    _________________________
+    |           |           |
+    |  before   |   after   |
+    |           |           |
+    |___________|___________|
+    |           |           |
+    |  before   |   after   |
+    |___________|___________|
+                ^
+                seam
+
+We compute the final geometry for every window up front, from the geometry
+the windows had when the seam was created, instead of resizing each window
+and chasing whatever the window manager gave us.
+*/
+
+import (
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/ewmh"
+    "github.com/BurntSushi/xgbutil/xrect"
+    "github.com/BurntSushi/xgbutil/xwindow"
+
+    "github.com/justjake/j3/plan"
+    "github.com/justjake/j3/wm"
+
+    "fmt"
+)
+
+// one window along a seam
+type SeamWindow struct {
+    Window  *xwindow.Window
+    // which of this window's edges lies on the seam
+    Edge    wm.Direction
+    // position of the opposite edge, which stays put while the seam moves
+    Far     int
+    // decorated geometry of the window, as of the last time the seam moved
+    Decor   xrect.Rect
+    // size of the window manager's decorations around the client
+    FrameWidth, FrameHeight int
+    Hints   *plan.SizeHints
+}
+
+// put the window's `edge` on the seam, anchoring the opposite edge
+func (sw *SeamWindow) setEdge(edge wm.Direction) {
+    sw.Edge = edge
+    sw.Far = plan.EdgePos(sw.Decor, edge.Opposite())
+}
+
+// the size of the window's decorations along the seam's axis
+func (sw *SeamWindow) frameLength() int {
+    if sw.Edge == wm.Left || sw.Edge == wm.Right {
+        return sw.FrameWidth
+    }
+    return sw.FrameHeight
+}
+
+// the range of seam positions this window can accept, given its size hints.
+// lo_ok and hi_ok are false when that end of the range is unbounded.
+func (sw *SeamWindow) limits() (lo int, lo_ok bool, hi int, hi_ok bool) {
+    min, max := sw.Hints.Range(sw.Edge)
+    min += sw.frameLength()
+    if max > 0 {
+        max += sw.frameLength()
+    }
+    far := sw.Far
+
+    // windows before the seam grow as the seam moves forward,
+    // windows after the seam shrink
+    if sw.Edge == wm.Right || sw.Edge == wm.Bottom {
+        return far + min, true, far + max, max > 0
+    }
+    return far - max, max > 0, far - min, true
+}
+
+// true if the window fills the space it has with the seam at `pos` exactly,
+// without its size hints snapping it smaller
+func (sw *SeamWindow) fitsAt(pos int) bool {
+    return sw.Hints.Accepts(sw.Edge, abs(pos - sw.Far) - sw.frameLength())
+}
+
+// the geometry this window should have with the seam at `pos`.
+// The window's seam edge is always exactly at `pos`. Seam.Plan picks
+// positions where this fills the space exactly; only if no position suits
+// every window on the seam do size hints force the window to be smaller
+// than its space, and then the gap is left at the far edge.
+func (sw *SeamWindow) geometryAt(pos int) xrect.Rect {
+    far := sw.Far
+    length := abs(pos - far) - sw.frameLength()
+    length = sw.Hints.Constrain(sw.Edge, length) + sw.frameLength()
+
+    x, y := sw.Decor.X(), sw.Decor.Y()
+    w, h := sw.Decor.Width(), sw.Decor.Height()
+    switch sw.Edge {
+    case wm.Right:
+        w = length
+        x = pos - length
+    case wm.Left:
+        w = length
+        x = pos
+    case wm.Bottom:
+        h = length
+        y = pos - length
+    case wm.Top:
+        h = length
+        y = pos
+    }
+    return xrect.New(x, y, w, h)
+}
+
+type Seam struct {
+    // the edge of the originating window that lies on the seam.
+    // Windows on the same side have this edge on the seam, windows on the
+    // other side have the opposite edge on the seam
+    Direction   wm.Direction
+    // X coordinate for a vertical seam, Y for a horizontal one
+    Position    int
+    Windows     []*SeamWindow
+}
+
+func newSeamWindow(win *xwindow.Window, edge wm.Direction) (*SeamWindow, error) {
+    decor, geom, err := wm.Geometries(win)
+    if err != nil { return nil, err }
+    sw := &SeamWindow{
+        Window:         win,
+        Decor:          decor,
+        FrameWidth:     decor.Width() - geom.Width(),
+        FrameHeight:    decor.Height() - geom.Height(),
+        Hints:          wm.GetSizeHints(win),
+    }
+    sw.setEdge(edge)
+    return sw, nil
+}
+
+// Build the seam running along the `dir` edge of `win`.
+//
+// A managed window joins the seam if it has an edge within `epsilon` pixels
+// of the seam's line, and its span touches a window that is already on the
+// seam. Membership spreads along the line, so in a grid of windows the seam
+// runs the whole length of the row or column:
+//
+//    ___________
+//    |    |    |   dragging the middle column edge of any one of
+//    |____|____|   these windows moves the edge for all six,
+//    |    |    |   even though the top and bottom rows never touch
+//    |____|____|   each other
+//    |    |    |
+//    |____|____|
+//
+// Windows whose edge is on the line but that are separated from the seam by
+// a gap (or by a window that isn't on the line) are left alone.
+func NewSeam(X *xgbutil.XUtil, win *xwindow.Window, dir wm.Direction, epsilon int) (*Seam, error) {
+    origin, err := newSeamWindow(win, dir)
+    if err != nil {
+        return nil, fmt.Errorf("NewSeam: couldn't get geometry of %v: %v", win.Id, err)
+    }
+
+    seam := &Seam{dir, plan.EdgePos(origin.Decor, dir), []*SeamWindow{origin}}
+
+    // note that this is an intellegent request: the WM only gives us a list of visible, normal windows
+    // we don't have to worry about moving hidden windows or something
+    managed_windows, err := ewmh.ClientListGet(X)
+    if err != nil {
+        // we can safley ignore this error, because then we just fall back to resizing only this window
+        log.Printf("NewSeam: error getting EWMH client list: %v\n", err)
+        return seam, nil
+    }
+
+    // first, every window with an edge on the seam's line
+    candidates := []*SeamWindow{}
+    for _, candidate_id := range managed_windows {
+        // no need to run calculations for ourself!
+        if candidate_id == win.Id { continue }
+
+        candidate, err := newSeamWindow(xwindow.New(X, candidate_id), dir)
+        if err != nil {
+            log.Printf("NewSeam: couldn't get geometry for seam candidate %v: %v\n", candidate_id, err)
+            continue
+        }
+
+        switch {
+        case abs(plan.EdgePos(candidate.Decor, dir.Opposite()) - seam.Position) <= epsilon:
+            // across the line from us
+            candidate.setEdge(dir.Opposite())
+        case abs(plan.EdgePos(candidate.Decor, dir) - seam.Position) <= epsilon:
+            // on our side of the line
+            candidate.setEdge(dir)
+        default:
+            continue
+        }
+        candidates = append(candidates, candidate)
+    }
+
+    // then spread out from the origin window along the line, until no more
+    // candidates touch the seam
+    for grew := true; grew; {
+        grew = false
+        remaining := candidates[:0]
+        for _, candidate := range candidates {
+            if !seam.touches(candidate, epsilon) {
+                remaining = append(remaining, candidate)
+                continue
+            }
+            log.Printf("NewSeam: window %v - %v joins the seam on its %v edge\n", candidate.Window.Id, candidate.Decor, candidate.Edge)
+            seam.Windows = append(seam.Windows, candidate)
+            grew = true
+        }
+        candidates = remaining
+    }
+
+    return seam, nil
+}
+
+// true if `sw`'s span touches the span of any window already on the seam
+func (s *Seam) touches(sw *SeamWindow, slack int) bool {
+    for _, member := range s.Windows {
+        if plan.SpansOverlap(member.Decor, sw.Decor, s.Direction, slack) {
+            return true
+        }
+    }
+    return false
+}
+
+// clamp `pos` into the range of seam positions that every window on the
+// seam can accept
+func (s *Seam) Clamp(pos int) int {
+    for _, sw := range s.Windows {
+        lo, lo_ok, hi, hi_ok := sw.limits()
+        if hi_ok && pos > hi {
+            pos = hi
+        }
+        if lo_ok && pos < lo {
+            pos = lo
+        }
+    }
+    return pos
+}
+
+// how far Snap looks for a position that suits every window
+const maxSnapSearch = 4096
+
+// The seam position nearest to `pos` where every window on the seam that
+// snaps to size increments fits its space exactly, so the windows without
+// increments take up the slack and everything stays flush, like
+// plan.SplitLength does for two windows. If there's no such position, the
+// first window with increments (the origin window, if it has them) wins.
+func (s *Seam) Snap(pos int) int {
+    snapping := []*SeamWindow{}
+    // any position that works repeats every lcm of the increments
+    period := 1
+    for _, sw := range s.Windows {
+        if inc := sw.Hints.Increment(sw.Edge); inc > 1 {
+            snapping = append(snapping, sw)
+            period = lcm(period, inc)
+            if period > maxSnapSearch { period = maxSnapSearch }
+        }
+    }
+    if len(snapping) == 0 { return pos }
+
+    // search outwards from `pos`, staying inside the windows' limits
+    search := func(fits func(p int) bool) (int, bool) {
+        for d := 0; d <= period; d++ {
+            if p := pos - d; s.Clamp(p) == p && fits(p) { return p, true }
+            if p := pos + d; s.Clamp(p) == p && fits(p) { return p, true }
+        }
+        return pos, false
+    }
+
+    p, ok := search(func(p int) bool {
+        for _, sw := range snapping {
+            if !sw.fitsAt(p) { return false }
+        }
+        return true
+    })
+    if ok { return p }
+    p, _ = search(snapping[0].fitsAt)
+    return p
+}
+
+func gcd(a, b int) int {
+    for b != 0 {
+        a, b = b, a % b
+    }
+    return a
+}
+
+func lcm(a, b int) int {
+    return a / gcd(a, b) * b
+}
+
+// Compute the final geometry of every window on the seam with the seam moved
+// to `pos`, without touching any windows. The returned position is `pos`
+// clamped to what the windows' size hints allow, and snapped so they stay
+// flush (see Snap).
+func (s *Seam) Plan(pos int) (int, []xrect.Rect) {
+    pos = s.Snap(s.Clamp(pos))
+    geoms := make([]xrect.Rect, len(s.Windows))
+    for i, sw := range s.Windows {
+        geoms[i] = sw.geometryAt(pos)
+    }
+    return pos, geoms
+}
+
+// Move the seam to `pos`, resizing every window along it.
+// Windows that shrink are resized before windows that grow, so that the
+// windows never overlap along the way.
+func (s *Seam) MoveTo(pos int) error {
+    pos, geoms := s.Plan(pos)
+    if pos == s.Position {
+        return nil
+    }
+
+    growing := pos > s.Position
+    var first, second []int
+    for i, sw := range s.Windows {
+        // windows before the seam grow when the seam moves forward
+        before := sw.Edge == wm.Right || sw.Edge == wm.Bottom
+        if before == growing {
+            second = append(second, i)
+        } else {
+            first = append(first, i)
+        }
+    }
+
+    var failed error
+    for _, i := range append(first, second...) {
+        sw, geom := s.Windows[i], geoms[i]
+        // hold the window's far edge still, so it stays flush with
+        // whatever is on the other side even if the window manager rounds
+        err := wm.MoveResize(sw.Window, geom.X(), geom.Y(), geom.Width(), geom.Height(), wm.ResizeGravity(sw.Edge))
+        if err != nil {
+            log.Printf("Seam.MoveTo: couldn't configure %v to %v: %v\n", sw.Window.Id, geom, err)
+            failed = err
+            continue
+        }
+        sw.Decor = geom
+    }
+
+    s.Position = pos
+    return failed
+}
@@ -9,12 +9,20 @@ icon margin/padding padding values.
 Then, we create a window that covers the bounds of both rects using Bounds()
 and xwindow.Create.
 
-Finally, we compose the cross window from our rect geometries using ComposeShape.
+Finally, we compose the cross window from our rect geometries using ComposeShape,
+and paint its background and border according to the cross's Theme.
+
+A cross can have several layouts: sets of icons to show on each spar. Small
+target windows get a smaller layout. Switching layouts resizes and reshapes
+the same window, so the icon windows (and whatever actions are mapped to
+them) stay the same.
 */
 
 import (
     "github.com/BurntSushi/xgb/xproto"
     "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/icccm"
+    "github.com/BurntSushi/xgbutil/xgraphics"
     "github.com/BurntSushi/xgbutil/xwindow"
     "github.com/BurntSushi/xgbutil/xrect"
 
@@ -35,6 +43,10 @@ func RGB(c uint32) (color.RGBA) {
     return color.RGBA{r, g, b, 0xff}
 }
 
+// The WM_NAME of the cross window. Each icon window is named after its
+// icon, eg "Swap", so tools (like the integration tests) can find them.
+const CrossName = "j3 cross"
+
 func centerChild(child, parent xrect.Rect) (x, y int) {
     a := child
     b := parent
@@ -46,53 +58,136 @@ func centerChild(child, parent xrect.Rect) (x, y int) {
     return
 }
 
+// The icons to show on each spar of the cross, in order. Names that aren't
+// in Cross.Icons leave a blank space.
+type CrossLayout struct {
+    Name        string
+    Vertical    []string
+    Horizontal  []string
+}
+
 // the Cross (+) ui: two batches of icons indersecting at a 90* angle.
 // a large part of what j3 is about
 type Cross struct {
     Icons           map[string]*Icon
     Window          *xwindow.Window
-    IconSize        int
+    IconWidth       int
+    IconHeight      int
     IconMargin      int
     IconPadding     int
+    Theme           *Theme
+    // available layouts, biggest first, and the one currently shown
+    Layouts         []*CrossLayout
+    Layout          *CrossLayout
+    // the icon under the mouse, if any
+    hovered         *Icon
     imagesToBecomeIcons map[string]image.Image
 }
 
-func NewCross(icons map[string]image.Image, size, margin, padding int) (*Cross) {
+// Create a cross from icon images that are all `width` x `height`. A nil
+// theme means DefaultTheme.
+func NewCross(icons map[string]image.Image, theme *Theme, width, height, margin, padding int) (*Cross) {
+    if theme == nil {
+        theme = DefaultTheme()
+    }
     // now time to create the window!
-    cross := Cross{nil, nil, size, margin, padding, icons}
+    cross := Cross{nil, nil, width, height, margin, padding, theme, nil, nil, nil, icons}
     log.Printf("New Cross created: %v\n", cross)
     return &cross
 }
 
-// When a cross is declared in its object literal form, it may not have the appropriate window.
-// this function creates a new X11 window for the cross with the correct geometry depending 
-// on its Icon* parameters.
-func (c *Cross) CreateWindow(X *xgbutil.XUtil, icons_per_direction int, bg_color uint32) (*xwindow.Window, error) {
-
-    // calculate the dimensions of the spars of our cross +
-    // width/height reflect the vertical-orientation rectangle
-    width := c.IconMargin * 2 + c.IconSize
+// the length of a spar holding `icons_per_direction` icons that are each
+// `icon_length` long in the direction of the spar
+func (c *Cross) sparLength(icons_per_direction, icon_length int) int {
     // padding between the icons, margin on the edges
-    height := c.IconSize * icons_per_direction + c.IconPadding * (icons_per_direction-1) + c.IconMargin * 2
+    return icon_length * icons_per_direction + c.IconPadding * (icons_per_direction-1) + c.IconMargin * 2
+}
+
+// the size of the vertical and horizontal spars in the given layout
+func (c *Cross) spars(layout *CrossLayout) (vert, horiz xrect.Rect) {
+    vert = xrect.New(0, 0, c.IconMargin * 2 + c.IconWidth, c.sparLength(len(layout.Vertical), c.IconHeight))
+    horiz = xrect.New(0, 0, c.sparLength(len(layout.Horizontal), c.IconWidth), c.IconMargin * 2 + c.IconHeight)
+    return
+}
+
+// the width and height of the cross window in the given layout
+func (c *Cross) LayoutSize(layout *CrossLayout) (width, height int) {
+    vert, horiz := c.spars(layout)
+    bounds := Bound([]xrect.Rect{vert, horiz})
+    return bounds.Width(), bounds.Height()
+}
+
+// the biggest of c.Layouts that fits in a `width` x `height` area, or the
+// smallest layout if none of them do
+func (c *Cross) LayoutFor(width, height int) *CrossLayout {
+    for _, layout := range c.Layouts {
+        w, h := c.LayoutSize(layout)
+        if w <= width && h <= height {
+            return layout
+        }
+    }
+    return c.Layouts[len(c.Layouts) - 1]
+}
 
+// When a cross is declared in its object literal form, it may not have the appropriate window.
+// this function creates a new X11 window for the cross, and shows the first
+// of `layouts` on it.
+func (c *Cross) CreateWindow(X *xgbutil.XUtil, layouts []*CrossLayout) (*xwindow.Window, error) {
+    if len(layouts) == 0 {
+        return nil, errors.New("Cross: a cross needs at least one layout")
+    }
+    c.Layouts = layouts
 
-    // intitialize a basic window for the cross
+    // intitialize a basic window for the cross. SetLayout gives it the
+    // right size and shape
     win, err := xwindow.Generate(X)
     if err != nil { return nil, err }
 
-    win.Create(X.RootWin(), 0, 0, height, height, 
-        xproto.CwBackPixel | xproto.CwOverrideRedirect, bg_color, 1)
+    width, height := c.LayoutSize(layouts[0])
+    win.Create(X.RootWin(), 0, 0, width, height, 
+        xproto.CwBackPixel | xproto.CwOverrideRedirect, Pixel(c.Theme.Background), 1)
 
+    icccm.WmNameSet(X, win.Id, CrossName)
 
-    // the rects we will be adding together to form the shape of the cross
-    vert := xrect.New(0, 0, width, height)
-    horiz := xrect.New(0, 0, height, width)
-    struts := []xrect.Rect{vert, horiz}
+    // add the window to our cross struct
+    c.Window = win
 
-    geom, err := win.Geometry()
+    // create icons from our images, or the theme's replacements for them
+    if c.imagesToBecomeIcons != nil {
+        icons := make(map[string]*Icon, len(c.imagesToBecomeIcons))
+        for name, img := range c.imagesToBecomeIcons {
+            icon := NewIcon(X, c.Theme.Icon(name, img), win.Id)
+            icon.Theme = c.Theme
+            icccm.WmNameSet(X, icon.Window.Id, name)
+            icons[name] = icon
+        }
+        c.Icons = icons
+    } else {
+        return nil, errors.New("Cross: you must create crosses using the NewCross function (this cross has now iconsToBecomeImage)")
+    }
+
+    err = c.SetLayout(X, layouts[0])
     if err != nil { return nil, err }
 
+    return win, nil
+}
+
+// Resize and reshape the cross window for `layout`, and show only the icons
+// it names. Does nothing if `layout` is already showing.
+func (c *Cross) SetLayout(X *xgbutil.XUtil, layout *CrossLayout) error {
+    if layout == c.Layout {
+        return nil
+    }
+
+    width, height := c.LayoutSize(layout)
+    c.Window.Resize(width, height)
+
+    // the rects we will be adding together to form the shape of the cross
+    vert, horiz := c.spars(layout)
+    struts := []xrect.Rect{vert, horiz}
+
     // center struts over window
+    geom := xrect.New(0, 0, width, height)
     x, y := util.CenterChild(vert, geom)
     vert.XSet(x)
     vert.YSet(y)
@@ -101,37 +196,65 @@ func (c *Cross) CreateWindow(X *xgbutil.XUtil, icons_per_direction int, bg_color
     horiz.YSet(y)
 
     // build the cross shape from our friendly rectangles
-    err = ComposeShape(X, win.Id, struts)
-    if err != nil { return nil, err }
+    err := ComposeRoundedShape(X, c.Window.Id, struts, c.Theme.CornerRadius)
+    if err != nil { return err }
+    c.paint(X, width, height, struts)
 
-    // add the window to our cross struct
-    c.Window = win
+    // hide everything, then show the icons on this layout
+    c.Hover(0)
+    for _, icon := range c.Icons {
+        icon.Window.Unmap()
+    }
+    c.layoutIcons(layout.Horizontal, horiz.X() + c.IconMargin, horiz.Y() + c.IconMargin, 1, 0)
+    c.layoutIcons(layout.Vertical, vert.X() + c.IconMargin, vert.Y() + c.IconMargin, 0, 1)
 
-    // create icons from our images
-    clr := RGB(bg_color)
-    if c.imagesToBecomeIcons != nil {
-        icons := make(map[string]*Icon, len(c.imagesToBecomeIcons))
-        for name, img := range c.imagesToBecomeIcons {
-            icon := NewIcon(X, img, win.Id)
-            icon.Background = clr
-            icons[name] = icon
+    c.Layout = layout
+    return nil
+}
+
+// Paint the background and border of the cross, whose spars are `struts`.
+// The border follows the outline of the whole cross, not each spar, so
+// there's no border where the spars cross.
+func (c *Cross) paint(X *xgbutil.XUtil, width, height int, struts []xrect.Rect) {
+    t := c.Theme
+    bw := t.BorderWidth
+    inner := make([]xrect.Rect, len(struts))
+    for i, strut := range struts {
+        inner[i] = xrect.New(strut.X() + bw, strut.Y() + bw,
+            strut.Width() - 2 * bw, strut.Height() - 2 * bw)
+    }
+    inner_radius := max(t.CornerRadius - bw, 0)
+
+    ximg := xgraphics.New(X, image.Rect(0, 0, width, height))
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            clr := t.Border
+            for _, rect := range inner {
+                if insideRounded(rect, inner_radius, x, y) {
+                    clr = t.Background
+                    break
+                }
+            }
+            ximg.Set(x, y, clr)
         }
-        c.Icons = icons
-    } else {
-        return nil, errors.New("Cross: you must create crosses using the NewCross function (this cross has now iconsToBecomeImage)")
     }
 
-    return win, nil
+    // make the image the window's background, like Icon.Blend does
+    ximg.XSurfaceSet(c.Window.Id)
+    ximg.XDraw()
+    ximg.XPaint(c.Window.Id)
+    ximg.Destroy()
 }
 
-// show the appropraite icons in the correct positions
-func (c *Cross) LayoutHorizontalIcons(icon_names []string, offsetY int) {
-    deltaX := c.IconPadding + c.IconSize
-    offsetX := c.IconMargin
+// place the icons named in `icon_names` one after the other starting at
+// (x, y), stepping by one icon and its padding in direction (dx, dy)
+func (c *Cross) layoutIcons(icon_names []string, x, y, dx, dy int) {
+    delta_x := c.IconPadding + c.IconWidth
+    delta_y := c.IconPadding + c.IconHeight
 
     for i, name := range icon_names {
         if icon, ok := c.Icons[name]; ok {
-            icon.Move(offsetX + deltaX * i, offsetY)
+            icon.Move(x + dx * delta_x * i, y + dy * delta_y * i)
             icon.Window.Map()
         } else {
             log.Printf("Skipping image '%v': not found in icon store %v\n", name, c.Icons)
@@ -139,20 +262,54 @@ func (c *Cross) LayoutHorizontalIcons(icon_names []string, offsetY int) {
     }
 }
 
+// show the appropraite icons in the correct positions
+func (c *Cross) LayoutHorizontalIcons(icon_names []string, offsetY int) {
+    c.layoutIcons(icon_names, c.IconMargin, offsetY, 1, 0)
+}
+
 // Icons named in `icon_names` are positioned across the horizontal spar in the
 // order they were named. Icons that are not in c.Icons are skipped, leaving
 // thier space blank. In such a manner you can leave blank spaces by passing 
 // strings like "HurrDurr" in that position
 func (c *Cross) LayoutVerticalIcons(icon_names []string, offsetX int) {
-    deltaY := c.IconPadding + c.IconSize
-    offsetY := c.IconMargin
+    c.layoutIcons(icon_names, offsetX, c.IconMargin, 0, 1)
+}
 
-    for i, name := range icon_names {
-        if icon, ok := c.Icons[name]; ok {
-            icon.Move(offsetX, offsetY + deltaY * i)
-            icon.Window.Map()
-        } else {
-            log.Printf("Skipping image '%v': not found in icon store %v\n", name, c.Icons)
+// Highlight the icon whose window is `win`, and stop highlighting the one
+// that was highlighted before. Disabled icons don't light up, and any window
+// that isn't an icon (like 0, for none) just clears the highlight.
+// Returns the highlighted icon, or nil.
+func (c *Cross) Hover(win xproto.Window) *Icon {
+    var next *Icon
+    for _, icon := range c.Icons {
+        if icon.Window.Id == win && icon.State() != StateDisabled {
+            next = icon
+            break
         }
     }
+    if next == c.hovered {
+        return next
+    }
+
+    if c.hovered != nil {
+        c.hovered.SetState(StateNormal)
+    }
+    if next != nil {
+        next.SetState(StateHover)
+    }
+    c.hovered = next
+    return next
+}
+
+// destroy the cross window and all of its icons. The cross is useless
+// afterwards; make a new one with NewCross.
+func (c *Cross) Destroy() {
+    for _, icon := range c.Icons {
+        icon.Window.Destroy()
+    }
+    c.Icons = nil
+    if c.Window != nil {
+        c.Window.Destroy()
+        c.Window = nil
+    }
 }
@@ -0,0 +1,40 @@
+// work out how big to draw things on high-DPI screens
+
+package ui
+
+import (
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/xprop"
+
+    "log"
+    "strconv"
+    "strings"
+)
+
+// the DPI that icons are drawn for
+const BaseDPI = 96.0
+
+// The UI scale factor the user asked for through the Xft.dpi X resource,
+// eg 2.0 for "Xft.dpi: 192". If Xft.dpi isn't set, the scale is 1.
+func DPIScale(X *xgbutil.XUtil) float64 {
+    // X resources live in a string property on the root window,
+    // one "name:\tvalue" per line
+    resources, err := xprop.PropValStr(xprop.GetProperty(X, X.RootWin(), "RESOURCE_MANAGER"))
+    if err != nil {
+        return 1.0
+    }
+
+    for _, line := range strings.Split(resources, "\n") {
+        parts := strings.SplitN(line, ":", 2)
+        if len(parts) != 2 || strings.TrimSpace(parts[0]) != "Xft.dpi" {
+            continue
+        }
+        dpi, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+        if err != nil || dpi <= 0 {
+            log.Printf("DPIScale: ignoring bad Xft.dpi %q\n", parts[1])
+            return 1.0
+        }
+        return dpi / BaseDPI
+    }
+    return 1.0
+}
@@ -0,0 +1,90 @@
+// a picture of the window being dragged, that follows the pointer around
+
+package ui
+
+import (
+    "github.com/BurntSushi/xgb/shape"
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/ewmh"
+    "github.com/BurntSushi/xgbutil/icccm"
+    "github.com/BurntSushi/xgbutil/xgraphics"
+
+    "image"
+    "image/color"
+)
+
+// A DragIcon shows what's being dragged, even when the window itself is
+// hidden behind others: its icon if it has one, otherwise its title, drawn
+// small over the theme's background
+type DragIcon struct {
+    *Icon
+    // how far the icon sits below and to the right of the pointer, so it
+    // doesn't cover whatever the pointer is over
+    Offset  int
+    // mapped yet
+    shown   bool
+}
+
+// Create a hidden `size` pixel square drag icon for `win`
+func NewDragIcon(X *xgbutil.XUtil, win xproto.Window, theme *Theme, size int) *DragIcon {
+    img := windowPicture(X, win, theme, size)
+
+    icon := NewIcon(X, img, X.RootWin())
+    icon.Theme = theme
+    id := icon.Window.Id
+
+    // keep the window manager's hands off it
+    xproto.ChangeWindowAttributes(X.Conn(), id, xproto.CwOverrideRedirect, []uint32{1})
+    // and let the mouse go straight through, like ghosts do
+    shape.Rectangles(X.Conn(), shape.SoSet, shape.SkInput,
+        xproto.ClipOrderingUnsorted, id, 0, 0, nil)
+    icon.Blend()
+
+    return &DragIcon{Icon: icon, Offset: size / 2}
+}
+
+// the picture for `win`: _NET_WM_ICON or the WM_HINTS icon, otherwise its
+// title
+func windowPicture(X *xgbutil.XUtil, win xproto.Window, theme *Theme, size int) image.Image {
+    icon, err := xgraphics.FindIcon(X, win, size, size)
+    if err == nil {
+        return icon
+    }
+
+    title, err := ewmh.WmNameGet(X, win)
+    if err != nil || title == "" {
+        title, _ = icccm.WmNameGet(X, win)
+    }
+    return TextImage(title, size, size, max(size / 12, 1), contrasting(theme.Background))
+}
+
+// black or white, whichever stands out more against `bg`
+func contrasting(bg color.RGBA) color.RGBA {
+    // perceived brightness, out of 255
+    luma := (299 * int(bg.R) + 587 * int(bg.G) + 114 * int(bg.B)) / 1000
+    if luma > 127 {
+        return RGB(0x000000)
+    }
+    return RGB(0xffffff)
+}
+
+// move next to the pointer at rx, ry, in root coordinates. The first move
+// shows the icon, over everything else
+func (d *DragIcon) Follow(rx, ry int) {
+    d.Window.Move(rx + d.Offset, ry + d.Offset)
+    if !d.shown {
+        d.Raise()
+        d.Window.Map()
+        d.shown = true
+    }
+}
+
+// put the icon back on top, eg after something else was raised over it
+func (d *DragIcon) Raise() {
+    d.Window.Stack(xproto.StackModeAbove)
+}
+
+func (d *DragIcon) Destroy() {
+    d.Window.Destroy()
+}
@@ -0,0 +1,155 @@
+// a tiny built-in pixel font, for drawing window titles without a font file
+
+package ui
+
+import (
+    "image"
+    "image/color"
+    "strings"
+    "unicode"
+)
+
+// glyphs are 3 pixels wide and 5 tall, with a pixel of space after each one
+const (
+    glyphWidth  = 3
+    glyphHeight = 5
+    glyphCellW  = glyphWidth + 1
+    glyphCellH  = glyphHeight + 1
+)
+
+// each glyph's rows, top to bottom, as '#' for ink and '.' for space.
+// Lowercase letters are drawn as uppercase; anything else missing is '?'.
+var glyphs = map[rune]string{
+    'A': ".#.#.#####.##.#",
+    'B': "##.#.###.#.###.",
+    'C': ".###..#..#...##",
+    'D': "##.#.##.##.###.",
+    'E': "####..##.#..###",
+    'F': "####..##.#..#..",
+    'G': ".###..#.##.#.##",
+    'H': "#.##.#####.##.#",
+    'I': "###.#..#..#.###",
+    'J': "..#..#..##.#.#.",
+    'K': "#.##.###.#.##.#",
+    'L': "#..#..#..#..###",
+    'M': "#.########.##.#",
+    'N': "##.#.##.##.##.#",
+    'O': ".#.#.##.##.#.#.",
+    'P': "##.#.###.#..#..",
+    'Q': ".#.#.##.###..##",
+    'R': "##.#.###.#.##.#",
+    'S': ".###...#...###.",
+    'T': "###.#..#..#..#.",
+    'U': "#.##.##.##.####",
+    'V': "#.##.##.##.#.#.",
+    'W': "#.##.########.#",
+    'X': "#.##.#.#.#.##.#",
+    'Y': "#.##.#.#..#..#.",
+    'Z': "###..#.#.#..###",
+    '0': "####.##.##.####",
+    '1': ".#.##..#..#.###",
+    '2': "##...#.#.#..###",
+    '3': "##...#.#...###.",
+    '4': "#.##.####..#..#",
+    '5': "####..##...###.",
+    '6': ".###..####.####",
+    '7': "###..#.#..#..#.",
+    '8': "####.#####.####",
+    '9': "####.####..###.",
+    ' ': "...............",
+    '-': "......###......",
+    '.': ".............#.",
+    ':': "....#.....#....",
+    '_': "............###",
+    '/': "..#..#.#.#..#..",
+    '?': "##...#.#.....#.",
+}
+
+// the glyph for `r`
+func glyph(r rune) string {
+    if g, ok := glyphs[unicode.ToUpper(r)]; ok {
+        return g
+    }
+    return glyphs['?']
+}
+
+// Split `text` into lines of at most `width` characters, breaking between
+// words where it can
+func wrapText(text string, width int) []string {
+    lines := []string{}
+    line := ""
+    for _, word := range strings.Fields(text) {
+        if line != "" && len([]rune(line)) + 1 + len([]rune(word)) <= width {
+            line += " " + word
+            continue
+        }
+        if line != "" {
+            lines = append(lines, line)
+        }
+        // words longer than a line get chopped up
+        runes := []rune(word)
+        for len(runes) > width {
+            lines = append(lines, string(runes[:width]))
+            runes = runes[width:]
+        }
+        line = string(runes)
+    }
+    if line != "" {
+        lines = append(lines, line)
+    }
+    return lines
+}
+
+// Draw `text` in the pixel font, as big as it will go in a `width` x
+// `height` image with `pad` pixels clear around the edges. Text that won't
+// fit even at the smallest size is cut off. The rest of the image is
+// transparent.
+func TextImage(text string, width, height, pad int, clr color.Color) *image.RGBA {
+    img := image.NewRGBA(image.Rect(0, 0, width, height))
+    avail_w, avail_h := width - 2 * pad, height - 2 * pad
+    if avail_w < glyphWidth || avail_h < glyphHeight { return img }
+
+    // the biggest pixel size that fits every line, down to 1
+    size := min(avail_w / glyphWidth, avail_h / glyphHeight)
+    var lines []string
+    for ; size >= 1; size-- {
+        // the space after the last glyph on a line can hang off the edge
+        per_line := (avail_w + size) / (glyphCellW * size)
+        max_lines := (avail_h + size) / (glyphCellH * size)
+        lines = wrapText(text, per_line)
+        if len(lines) <= max_lines || size == 1 {
+            if len(lines) > max_lines { lines = lines[:max_lines] }
+            break
+        }
+    }
+    if len(lines) == 0 { return img }
+
+    // centered, as a block
+    block_h := len(lines) * glyphCellH * size - size
+    y := pad + (avail_h - block_h) / 2
+    for _, line := range lines {
+        runes := []rune(line)
+        line_w := len(runes) * glyphCellW * size - size
+        x := pad + (avail_w - line_w) / 2
+        for _, r := range runes {
+            drawGlyph(img, glyph(r), x, y, size, clr)
+            x += glyphCellW * size
+        }
+        y += glyphCellH * size
+    }
+    return img
+}
+
+// draw one glyph with its top left corner at x, y, each of its pixels
+// `size` pixels square
+func drawGlyph(img *image.RGBA, g string, x, y, size int, clr color.Color) {
+    for i, ink := range g {
+        if ink != '#' { continue }
+        gx, gy := x + (i % glyphWidth) * size, y + (i / glyphWidth) * size
+        for dy := 0; dy < size; dy++ {
+            for dx := 0; dx < size; dx++ {
+                img.Set(gx + dx, gy + dy, clr)
+            }
+        }
+    }
+}
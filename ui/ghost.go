@@ -0,0 +1,73 @@
+// outlines showing where a window will end up
+
+package ui
+
+import (
+    "github.com/BurntSushi/xgb/shape"
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/ewmh"
+    "github.com/BurntSushi/xgbutil/xrect"
+    "github.com/BurntSushi/xgbutil/xwindow"
+)
+
+// how opaque ghosts are, under a compositing manager. Without one they're
+// solid, which is fine since they're only outlines.
+const ghostOpacity = 0.6
+
+// A Ghost is an outline of a rectangle on the screen, drawn in the theme's
+// hover color, used to preview where an action will put a window.
+type Ghost struct {
+    Window      *xwindow.Window
+    // how thick the outline is
+    Thickness   int
+}
+
+// create a hidden ghost
+func NewGhost(X *xgbutil.XUtil, theme *Theme, thickness int) (*Ghost, error) {
+    win, err := xwindow.Generate(X)
+    if err != nil { return nil, err }
+
+    win.Create(X.RootWin(), 0, 0, 1, 1,
+        xproto.CwBackPixel | xproto.CwOverrideRedirect, Pixel(theme.Hover), 1)
+
+    // not all compositors handle this, so don't worry if it doesn't stick
+    ewmh.WmWindowOpacitySet(X, win.Id, ghostOpacity)
+
+    // let the mouse go straight through, so ghosts don't get in the way of
+    // finding the window or icon under the pointer
+    shape.Rectangles(X.Conn(), shape.SoSet, shape.SkInput,
+        xproto.ClipOrderingUnsorted, win.Id, 0, 0, nil)
+
+    return &Ghost{win, thickness}, nil
+}
+
+// outline `rect`, which is in root window coordinates
+func (g *Ghost) Show(rect xrect.Rect) error {
+    w, h := rect.Width(), rect.Height()
+    t := min(g.Thickness, min(w, h) / 2)
+    if t < 1 { t = 1 }
+
+    // the four sides of the outline, relative to the ghost window
+    sides := []xrect.Rect{
+        xrect.New(0, 0, w, t),
+        xrect.New(0, h - t, w, t),
+        xrect.New(0, 0, t, h),
+        xrect.New(w - t, 0, t, h),
+    }
+    err := ComposeRoundedShape(g.Window.X, g.Window.Id, sides, 0)
+    if err != nil { return err }
+
+    g.Window.MoveResize(rect.X(), rect.Y(), w, h)
+    g.Window.Stack(xproto.StackModeAbove)
+    g.Window.Map()
+    return nil
+}
+
+func (g *Ghost) Hide() {
+    g.Window.Unmap()
+}
+
+func (g *Ghost) Destroy() {
+    g.Window.Destroy()
+}
@@ -16,13 +16,14 @@ import (
 type IconState int
 const (
     StateNormal = iota
-    // the icon is blended against the background at 50% opacity
+    // the icon is blended against the background at the theme's
+    // disabled opacity
     StateDisabled
     // the icon is unmapped as a window
     StateHidden
-
-    // opacity multiplier
-    iconDisabledShade = 0.35
+    // the mouse is over the icon: it is drawn over the theme's hover color,
+    // so the user can see which action they're about to drop onto
+    StateHover
 )
 
 type Icon struct {
@@ -31,7 +32,7 @@ type Icon struct {
     Window  *xwindow.Window     // window that the icon is drawn to
     ximage  *xgraphics.Image    // X11 image swap buffer, used to paint Image to Window
     state   IconState
-    Background color.Color
+    Theme   *Theme
 }
 
 // Create a new Icon from an image, with a given X11 window parent
@@ -39,15 +40,18 @@ func NewIcon(X *xgbutil.XUtil, img image.Image, parent xproto.Window) *Icon {
     ximg := xgraphics.NewConvert(X, img)
     win := ximg.Window(parent)
     fader := fadeImage{img, 1.0}
-    icn := Icon{&fader, parent, win, ximg, StateNormal, color.RGBA{0xcc, 0xcc, 0xcc, 0xff}}
+    icn := Icon{&fader, parent, win, ximg, StateNormal, DefaultTheme()}
     return &icn
 }
 
-// get the pixels directly behind the icon
-// right now just returns an image.Uniform hard-coded to the right shade of grey
-// TODO actually do this
+// get the pixels directly behind the icon. Icons sit well inside the
+// border of the cross, so that's always the theme's flat background color,
+// or its hover color when the mouse is over the icon.
 func (icn *Icon) getBackground() image.Image {
-    bg_color := icn.Background
+    bg_color := icn.Theme.Background
+    if icn.state == StateHover {
+        bg_color = icn.Theme.Hover
+    }
     bg := image.NewUniform(bg_color)
     return bg
 }
@@ -80,6 +84,11 @@ func (icn *Icon) Move(x, y int) {
     icn.Blend()
 }
 
+// the icon's current state
+func (icn *Icon) State() IconState {
+    return icn.state
+}
+
 // change image states
 func (icn *Icon) SetState(newstate IconState) {
     if newstate == icn.state {
@@ -94,10 +103,10 @@ func (icn *Icon) SetState(newstate IconState) {
 
     // fade out disabled icons
     if newstate == StateDisabled {
-        icn.Image.Factor = iconDisabledShade
+        icn.Image.Factor = icn.Theme.DisabledOpacity
     }
 
-    if newstate == StateNormal {
+    if newstate == StateNormal || newstate == StateHover {
         icn.Image.Factor = 1.0
     }
 
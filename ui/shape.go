@@ -10,6 +10,7 @@ import (
     "github.com/BurntSushi/xgbutil/xrect"
 
     "log"
+    "math"
 )
 
 // extract the top-left and bottom-right points of an xrect as a 4-tuple:  x, y, x2, y2
@@ -43,6 +44,14 @@ func ComposeShape(X *xgbutil.XUtil, dst xproto.Window, rects []xrect.Rect) (err
 
     combine_bounds := make([]shape.CombineCookie, len(rects))
     combine_clip   := make([]shape.CombineCookie, len(rects))
+    // the scratch windows we take each rect's shape from. Crosses are
+    // reshaped whenever they change layout, so clean up after ourselves
+    scratch := make([]*xwindow.Window, 0, len(rects))
+    defer func() {
+        for _, win := range scratch {
+            win.Destroy()
+        }
+    }()
 
     var operation shape.Op
 
@@ -54,6 +63,7 @@ func ComposeShape(X *xgbutil.XUtil, dst xproto.Window, rects []xrect.Rect) (err
             return err
         }
         win.Create(X.RootWin(), rect.X(), rect.Y(), rect.Width(), rect.Height(), xproto.CwBackPixel, 0xffffff)
+        scratch = append(scratch, win)
 
         // choose operation. on the first one, we want to set the shape.
         if i == 0 {
@@ -70,6 +80,80 @@ func ComposeShape(X *xgbutil.XUtil, dst xproto.Window, rects []xrect.Rect) (err
         combine_kind = shape.Kind(shape.SkClip)
         combine_clip[i] = shape.CombineChecked(X.Conn(), operation, combine_kind, combine_kind, dst, x, y, win.Id)
     }
+
+    // the scratch windows have to outlive the combine requests
+    for i := range rects {
+        if err = combine_bounds[i].Check(); err != nil { return err }
+        if err = combine_clip[i].Check(); err != nil { return err }
+    }
     return nil
 }
     
+
+// how far in from each side of `rect` row `row` starts, once the corners
+// are rounded off to `radius`
+func roundedInset(rect xrect.Rect, radius, row int) int {
+    radius = min(radius, min(rect.Width(), rect.Height()) / 2)
+    if radius <= 0 {
+        return 0
+    }
+
+    // vertical distance from the middle of the row to the corner's center
+    var d float64
+    r := float64(radius)
+    switch {
+    case row < radius:
+        d = r - (float64(row) + 0.5)
+    case row >= rect.Height() - radius:
+        d = float64(row) + 0.5 - float64(rect.Height() - radius)
+    default:
+        return 0
+    }
+    return int(r - math.Sqrt(r * r - d * d) + 0.5)
+}
+
+// true if pixel (x, y) is inside `rect` with its corners rounded to `radius`
+func insideRounded(rect xrect.Rect, radius, x, y int) bool {
+    min_x, min_y, max_x, max_y := coords(rect)
+    if y < min_y || y >= max_y {
+        return false
+    }
+    inset := roundedInset(rect, radius, y - min_y)
+    return x >= min_x + inset && x < max_x - inset
+}
+
+// like ComposeShape, but with the corners of each rectangle rounded off to
+// `radius`. The shape is built from one rectangle per rounded row, so it
+// matches insideRounded pixel for pixel. With a radius of 0 this is just a
+// cheaper ComposeShape, since it needs no scratch windows.
+func ComposeRoundedShape(X *xgbutil.XUtil, dst xproto.Window, rects []xrect.Rect, radius int) error {
+    rows := []xproto.Rectangle{}
+    for _, rect := range rects {
+        min_x, min_y, max_x, _ := coords(rect)
+        for row := 0; row < rect.Height(); row++ {
+            inset := roundedInset(rect, radius, row)
+            // rows without an inset make up the straight middle of the
+            // rect, so send them as one tall rectangle
+            height := 1
+            if inset == 0 {
+                for row + height < rect.Height() && roundedInset(rect, radius, row + height) == 0 {
+                    height++
+                }
+            }
+            rows = append(rows, xproto.Rectangle{
+                X:      int16(min_x + inset),
+                Y:      int16(min_y + row),
+                Width:  uint16(max(max_x - min_x - 2 * inset, 0)),
+                Height: uint16(height),
+            })
+            row += height - 1
+        }
+    }
+
+    for _, kind := range []shape.Kind{shape.SkBounding, shape.SkClip} {
+        err := shape.RectanglesChecked(X.Conn(), shape.SoSet, kind,
+            xproto.ClipOrderingUnsorted, dst, 0, 0, rows).Check()
+        if err != nil { return err }
+    }
+    return nil
+}
@@ -0,0 +1,53 @@
+// colors, borders and icons for the cross and everything on it
+
+package ui
+
+import (
+    "image"
+    "image/color"
+)
+
+// How the cross and its icons look
+type Theme struct {
+    // behind the icons
+    Background      color.RGBA
+    // behind the icon under the mouse
+    Hover           color.RGBA
+    // opacity of icons that are disabled
+    DisabledOpacity float64
+    // the outline around the cross. A zero BorderWidth means no border
+    Border          color.RGBA
+    BorderWidth     int
+    // how round the ends of the spars are
+    CornerRadius    int
+    // images to use instead of the icons the cross was created with,
+    // by icon name. These should be the same size as the originals.
+    Icons           map[string]image.Image
+}
+
+// the theme j3 has always had: a flat grey cross
+func DefaultTheme() *Theme {
+    return &Theme{
+        Background:         RGB(0x262626),
+        Hover:              RGB(0x4e4e4e),
+        DisabledOpacity:    0.35,
+        Border:             RGB(0x000000),
+        BorderWidth:        0,
+        CornerRadius:       0,
+    }
+}
+
+// the image for icon `name`: the theme's replacement if it has one,
+// otherwise `img`
+func (t *Theme) Icon(name string, img image.Image) image.Image {
+    if replacement, ok := t.Icons[name]; ok {
+        return replacement
+    }
+    return img
+}
+
+// convert a color to the pixel value X uses for it on a 24-bit TrueColor
+// visual, for things like CwBackPixel
+func Pixel(c color.RGBA) uint32 {
+    return uint32(c.R) << 16 | uint32(c.G) << 8 | uint32(c.B)
+}
@@ -30,6 +30,24 @@ func CenterChild(child, parent Rect) (x, y int) {
     return
 }
 
+// the position closest to (x, y) that keeps rect A entirely inside rect B.
+// If A is bigger than B, it gets lined up with B's top-left corner.
+func ClampInside(a Rect, x, y int, b Rect) (int, int) {
+    if x + a.Width() > b.X() + b.Width() {
+        x = b.X() + b.Width() - a.Width()
+    }
+    if y + a.Height() > b.Y() + b.Height() {
+        y = b.Y() + b.Height() - a.Height()
+    }
+    if x < b.X() {
+        x = b.X()
+    }
+    if y < b.Y() {
+        y = b.Y()
+    }
+    return x, y
+}
+
 func RectEquals(a, b Rect) bool {
     return a.X() == b.X() && a.Y() == b.Y() && a.Width() == b.Width() && a.Height() == b.Height()
 }
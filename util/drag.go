@@ -0,0 +1,182 @@
+package util
+
+/*
+Mouse drags, like mousebind.Drag, except that pressing and releasing other
+buttons in the middle of a drag doesn't end it. Scroll wheel presses go to
+the drag's Press handler instead, so the scroll wheel can do things
+mid-drag. Escape, or pressing a second button, cancels the drag.
+
+mousebind.Drag ends a drag on any ButtonRelease its grab window gets, and
+every drag bound on the same grab window shares those handlers. So each
+Drag grabs the pointer on a private window of its own instead.
+*/
+
+import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/keybind"
+    "github.com/BurntSushi/xgbutil/mousebind"
+    "github.com/BurntSushi/xgbutil/xevent"
+    "github.com/BurntSushi/xgbutil/xwindow"
+)
+
+// handles other buttons pressed during a drag, eg 4 and 5 for the scroll
+// wheel. rx, ry is the pointer position in root coordinates.
+type DragPressFun func(X *xgbutil.XUtil, button xproto.Button, rx, ry int)
+
+// runs instead of the end handler when the user cancels a drag
+type DragCancelFun func(X *xgbutil.XUtil)
+
+type Drag struct {
+    X       *xgbutil.XUtil
+    begin   xgbutil.MouseDragBeginFun
+    step    xgbutil.MouseDragFun
+    end     xgbutil.MouseDragFun
+    press   DragPressFun
+    cancel  DragCancelFun
+
+    // the button that started the drag, and will end it
+    button  xproto.Button
+    active  bool
+    // the drag was cancelled, but we hold on to the pointer until the
+    // button comes up, so nobody else sees a stray release
+    cancelled bool
+    // holds the pointer grab, so the drag's events don't mix with
+    // anything else listening on the root window
+    grabWin *xwindow.Window
+}
+
+// Bind a drag to `combo` (eg "Mod1-Shift-1") on `win`. `begin`, `step` and
+// `end` work like they do for mousebind.Drag; `press` and `cancel` may be
+// nil. Call Destroy to unbind it.
+func BindDrag(X *xgbutil.XUtil, win xproto.Window, combo string,
+    begin xgbutil.MouseDragBeginFun, step, end xgbutil.MouseDragFun,
+    press DragPressFun, cancel DragCancelFun) (*Drag, error) {
+
+    _, button, err := mousebind.ParseString(X, combo)
+    if err != nil { return nil, err }
+
+    // a window can only hold a grab while it's viewable, so map it where
+    // nobody will ever see it
+    grab_win, err := xwindow.Generate(X)
+    if err != nil { return nil, err }
+    err = grab_win.CreateChecked(X.RootWin(), -1, -1, 1, 1, xproto.CwOverrideRedirect, 1)
+    if err != nil { return nil, err }
+    grab_win.Map()
+
+    d := &Drag{X: X, begin: begin, step: step, end: end, press: press, cancel: cancel,
+        button: button, grabWin: grab_win}
+
+    xevent.MotionNotifyFun(d.handleMotion).Connect(X, grab_win.Id)
+    xevent.ButtonPressFun(d.handlePress).Connect(X, grab_win.Id)
+    xevent.ButtonReleaseFun(d.handleRelease).Connect(X, grab_win.Id)
+    xevent.KeyPressFun(d.handleKey).Connect(X, grab_win.Id)
+
+    err = mousebind.ButtonPressFun(d.start).Connect(X, win, combo, false, true)
+    if err != nil {
+        d.Destroy()
+        return nil, err
+    }
+    return d, nil
+}
+
+// true while the drag is happening
+func (d *Drag) Active() bool {
+    return d.active && !d.cancelled
+}
+
+// Stop listening for the drag. The button binding itself goes away with
+// mousebind.Detach on the window it was bound on.
+func (d *Drag) Destroy() {
+    if d.active {
+        mousebind.UngrabPointer(d.X)
+        keybind.UngrabKeyboard(d.X)
+        d.active, d.cancelled = false, false
+    }
+    xevent.Detach(d.X, d.grabWin.Id)
+    d.grabWin.Destroy()
+}
+
+func (d *Drag) start(X *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
+    if d.active { return }
+
+    cont, cursor := d.begin(X, int(ev.RootX), int(ev.RootY), int(ev.EventX), int(ev.EventY))
+    if !cont { return }
+
+    ok, err := mousebind.GrabPointer(X, d.grabWin.Id, X.RootWin(), cursor)
+    if err != nil || !ok {
+        // nothing else will end the drag, so end it now
+        d.end(X, int(ev.RootX), int(ev.RootY), int(ev.EventX), int(ev.EventY))
+        return
+    }
+    d.active = true
+
+    // for Escape. If someone else has the keyboard, the drag still works,
+    // it just can't be cancelled from it
+    keybind.GrabKeyboard(X, d.grabWin.Id)
+}
+
+// Stop the drag without running the end handler
+func (d *Drag) abort(X *xgbutil.XUtil) {
+    d.cancelled = true
+    keybind.UngrabKeyboard(X)
+    if d.cancel != nil {
+        d.cancel(X)
+    }
+}
+
+func (d *Drag) handleMotion(X *xgbutil.XUtil, ev xevent.MotionNotifyEvent) {
+    if !d.Active() { return }
+
+    // skip to the newest motion event, like mousebind does, so slow steps
+    // don't leave us trailing behind the pointer
+    last := ev
+    X.Sync()
+    xevent.Read(X, false)
+    for i, ee := range xevent.Peek(X) {
+        if ee.Err != nil { continue }
+        if mn, ok := ee.Event.(xproto.MotionNotifyEvent); ok && mn.Event == ev.Event {
+            last = xevent.MotionNotifyEvent{MotionNotifyEvent: &mn}
+            // dequeue newest first, so the indices stay valid
+            defer func(i int) { xevent.DequeueAt(X, i) }(i)
+        }
+    }
+    X.TimeSet(last.Time)
+
+    d.step(X, int(last.RootX), int(last.RootY), int(last.EventX), int(last.EventY))
+}
+
+func (d *Drag) handlePress(X *xgbutil.XUtil, ev xevent.ButtonPressEvent) {
+    if !d.Active() || ev.Detail == d.button { return }
+
+    // 4 and up are the scroll wheel; a real second button cancels
+    if ev.Detail <= 3 {
+        d.abort(X)
+        return
+    }
+    if d.press != nil {
+        d.press(X, ev.Detail, int(ev.RootX), int(ev.RootY))
+    }
+}
+
+func (d *Drag) handleKey(X *xgbutil.XUtil, ev xevent.KeyPressEvent) {
+    if !d.Active() { return }
+    for _, code := range keybind.StrToKeycodes(X, "Escape") {
+        if ev.Detail == code {
+            d.abort(X)
+            return
+        }
+    }
+}
+
+func (d *Drag) handleRelease(X *xgbutil.XUtil, ev xevent.ButtonReleaseEvent) {
+    if !d.active || ev.Detail != d.button { return }
+
+    cancelled := d.cancelled
+    d.active, d.cancelled = false, false
+    mousebind.UngrabPointer(X)
+    if cancelled { return }
+
+    keybind.UngrabKeyboard(X)
+    d.end(X, int(ev.RootX), int(ev.RootY), int(ev.EventX), int(ev.EventY))
+}
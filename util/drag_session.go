@@ -0,0 +1,197 @@
+package util
+
+/*
+The state of one drag-and-drop operation at a time: the window being
+dragged (Incoming), the window it would land on (Target), and the icon on
+the cross under the pointer (Icon).
+
+    idle -> armed -> over desktop <-> over target <-> over icon
+                          |                |              |
+                          v                v              v
+                     cancelled/committed (from any of the three)
+
+Every move goes through one of the transition methods, which refuse moves
+the diagram doesn't have. Cancelled and committed drags can be armed again.
+
+Drags that don't drop on anything, like resizing, commit over the desktop.
+Drags that do only commit over an icon; the session leaves that to them.
+*/
+
+import (
+    "github.com/BurntSushi/xgb/xproto"
+
+    "errors"
+    "fmt"
+)
+
+type DragState int
+
+const (
+    DragIdle DragState = iota
+    // the button is down on a window, but the pointer hasn't moved yet
+    DragArmed
+    // dragging with nothing to drop on
+    DragOverDesktop
+    DragOverTarget
+    // over a target, and over one of the cross's icons on top of it
+    DragOverIcon
+    DragCancelled
+    DragCommitted
+)
+
+func (s DragState) String() string {
+    switch s {
+    case DragIdle:        return "idle"
+    case DragArmed:       return "armed"
+    case DragOverDesktop: return "over desktop"
+    case DragOverTarget:  return "over target"
+    case DragOverIcon:    return "over icon"
+    case DragCancelled:   return "cancelled"
+    case DragCommitted:   return "committed"
+    }
+    return fmt.Sprintf("DragState(%d)", int(s))
+}
+
+// the states each state can move to
+var dragTransitions = map[DragState][]DragState{
+    DragIdle:        {DragArmed},
+    DragArmed:       {DragOverDesktop, DragOverTarget, DragCancelled},
+    DragOverDesktop: {DragOverTarget, DragCancelled, DragCommitted},
+    DragOverTarget:  {DragOverDesktop, DragOverTarget, DragOverIcon, DragCancelled, DragCommitted},
+    DragOverIcon:    {DragOverDesktop, DragOverTarget, DragOverIcon, DragCancelled, DragCommitted},
+    DragCancelled:   {DragArmed},
+    DragCommitted:   {DragArmed},
+}
+
+// runs after the session moves into a state, with the state it left
+type DragHook func(s *DragSession, from DragState)
+
+// One hook for each state a session can move into. Any of them may be nil.
+type DragHooks struct {
+    Armed       DragHook
+    OverDesktop DragHook
+    OverTarget  DragHook
+    OverIcon    DragHook
+    Cancelled   DragHook
+    Committed   DragHook
+}
+
+type DragSession struct {
+    hooks    DragHooks
+    state    DragState
+    incoming xproto.Window
+    target   xproto.Window
+    icon     xproto.Window
+}
+
+func NewDragSession(hooks DragHooks) *DragSession {
+    return &DragSession{hooks: hooks}
+}
+
+func (s *DragSession) State() DragState { return s.state }
+
+// zero until the session is armed
+func (s *DragSession) Incoming() xproto.Window { return s.incoming }
+
+// zero unless the session is over a target or one of its icons
+func (s *DragSession) Target() xproto.Window { return s.target }
+
+// zero unless the session is over an icon
+func (s *DragSession) Icon() xproto.Window { return s.icon }
+
+// true between Arm and Cancel or Commit
+func (s *DragSession) Active() bool {
+    switch s.state {
+    case DragArmed, DragOverDesktop, DragOverTarget, DragOverIcon:
+        return true
+    }
+    return false
+}
+
+// Start dragging `incoming`
+func (s *DragSession) Arm(incoming xproto.Window) error {
+    if incoming == 0 {
+        return errors.New("DragSession: can't arm without an incoming window")
+    }
+    return s.move(DragArmed, func() {
+        s.incoming, s.target, s.icon = incoming, 0, 0
+    })
+}
+
+// The pointer moved somewhere with nothing to drop on
+func (s *DragSession) Leave() error {
+    return s.move(DragOverDesktop, func() {
+        s.target, s.icon = 0, 0
+    })
+}
+
+// The pointer moved over `target`, or the user picked it from a stack of
+// windows under the pointer
+func (s *DragSession) Enter(target xproto.Window) error {
+    if target == 0 || target == s.incoming {
+        return fmt.Errorf("DragSession: can't drop window %v on %v", s.incoming, target)
+    }
+    return s.move(DragOverTarget, func() {
+        s.target, s.icon = target, 0
+    })
+}
+
+// The pointer moved onto `icon` on the current target's cross. Zero means
+// it moved off the icons, back onto the target.
+func (s *DragSession) Hover(icon xproto.Window) error {
+    if icon == 0 {
+        if s.state != DragOverIcon {
+            return fmt.Errorf("DragSession: can't leave an icon while %v", s.state)
+        }
+        return s.move(DragOverTarget, func() { s.icon = 0 })
+    }
+    // Enter is the way onto a target
+    if s.state != DragOverTarget && s.state != DragOverIcon {
+        return fmt.Errorf("DragSession: can't hover an icon while %v", s.state)
+    }
+    return s.move(DragOverIcon, func() { s.icon = icon })
+}
+
+// Give up on the drag. The session keeps its windows for the Cancelled
+// hook, until it's armed again.
+func (s *DragSession) Cancel() error {
+    return s.move(DragCancelled, nil)
+}
+
+// Finish the drag, eg by dropping the incoming window on the target. The
+// session keeps its windows for the Committed hook, until it's armed again.
+func (s *DragSession) Commit() error {
+    return s.move(DragCommitted, nil)
+}
+
+// Move to `to` if the current state allows it, applying `update` and then
+// running `to`'s hook
+func (s *DragSession) move(to DragState, update func()) error {
+    from := s.state
+    allowed := false
+    for _, next := range dragTransitions[from] {
+        if next == to { allowed = true }
+    }
+    if !allowed {
+        return fmt.Errorf("DragSession: can't go from %v to %v", from, to)
+    }
+
+    if update != nil { update() }
+    s.state = to
+    if hook := s.hooks.hook(to); hook != nil {
+        hook(s, from)
+    }
+    return nil
+}
+
+func (h DragHooks) hook(state DragState) DragHook {
+    switch state {
+    case DragArmed:       return h.Armed
+    case DragOverDesktop: return h.OverDesktop
+    case DragOverTarget:  return h.OverTarget
+    case DragOverIcon:    return h.OverIcon
+    case DragCancelled:   return h.Cancelled
+    case DragCommitted:   return h.Committed
+    }
+    return nil
+}
@@ -3,9 +3,17 @@ package wm
 /* Move.go
    handles moving windows about using EWMH interaction commands
    I think we can usually just use xwindow.Window objects for convinience
+
+   The layout math lives in the plan package. The functions here read the
+   windows involved from X, hand them to a planner, and apply the result.
    */
 import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil/xrect"
     "github.com/BurntSushi/xgbutil/xwindow"
+
+    "github.com/justjake/j3/plan"
+
     logLib "log"
     "os"
 )
@@ -35,194 +43,189 @@ var Actions = map[string]WindowInteraction{
     "Swap"  :  Swap,
 }
 
-func splitVertical(target, incoming *xwindow.Window, incomingOnTop bool) error {
-    bounds, err := target.DecorGeometry()
-    if err != nil {
-        log.Printf("splitVertical: error getting bounds of target: %v\n", err)
-        return err
-    }
+// the same actions as dry runs, for previewing what they would do
+var Plans = map[string]Planner{
+    "SplitTop"    :  PlanSplitTop,
+    "SplitRight"  :  PlanSplitRight,
+    "SplitBottom" :  PlanSplitBottom,
+    "SplitLeft"   :  PlanSplitLeft,
 
-    bottom_height := bounds.Height() / 2
-    top_height := bounds.Height() - bottom_height
+    "ShoveTop"    :  PlanShoveTop,
+    "ShoveRight"  :  PlanShoveRight,
+    "ShoveBottom" :  PlanShoveBottom,
+    "ShoveLeft"   :  PlanShoveLeft,
 
-    var top, bottom *xwindow.Window
-    if incomingOnTop {
-        top = incoming
-        bottom = target
-    } else {
-        top = target
-        bottom = incoming
-    }
+    "Swap"  :  PlanSwap,
+}
 
-    // target goes on bottom...
-    err = MoveResize(bottom, bounds.X(), bounds.Y() + top_height, bounds.Width(), bottom_height)
-    if err != nil {
-        log.Printf("splitVertical: error configuring bottom: %v\n", err)
-        return err
-    }
+// Where an action will put one window: its frame geometry, in root
+// window coordinates
+type Placement struct {
+    Window  *xwindow.Window
+    Rect    xrect.Rect
+}
 
-    // and incoming on top
-    err = MoveResize(top, bounds.X(), bounds.Y(), bounds.Width(), top_height)
-    if err != nil {
-        log.Printf("splitVertical: error configuring top: %v\n", err)
-        return err
+// Everything an action will do, as a list of windows to move and resize,
+// in the order to do it in
+type Plan []Placement
+
+// Works out what an action would do, without touching any windows
+type Planner func(target, incoming *xwindow.Window) (Plan, error)
+
+// Move and resize every window in the plan, in order
+func (p Plan) Apply() error {
+    for _, placement := range p {
+        rect := placement.Rect
+        err := MoveResize(placement.Window, rect.X(), rect.Y(), rect.Width(), rect.Height(), xproto.GravityNorthWest)
+        if err != nil {
+            log.Printf("Plan.Apply: error configuring %v: %v\n", placement.Window.Id, err)
+            return err
+        }
     }
-
-    // cool
     return nil
 }
 
-// cutting windows in half on the Y axis
-func splitHorizontal(target, incoming *xwindow.Window, incomingOnLeft bool) error {
-    bounds, err := target.DecorGeometry()
-    if err != nil {
-        log.Printf("splitHorizontal: error getting bounds of target: %v\n", err)
-        return err
-    }
+// plan an action and carry it out
+func execute(planner Planner, target, incoming *xwindow.Window) error {
+    p, err := planner(target, incoming)
+    if err != nil { return err }
+    return p.Apply()
+}
 
-    left_width := bounds.Width() / 2
-    right_width := bounds.Width() - left_width
+// Directions come from the plan package. These let window manager code
+// keep saying wm.Top
+type Direction = plan.Direction
+const (
+    Top     = plan.Top
+    Right   = plan.Right
+    Bottom  = plan.Bottom
+    Left    = plan.Left
+)
 
-    var left, right *xwindow.Window
-    if incomingOnLeft {
-        left = incoming
-        right = target
-    } else {
-        left = target
-        right = incoming
-    }
+// read everything a planner needs to know about a window from X
+func Describe(win *xwindow.Window) (plan.Window, error) {
+    frame, err := FrameGeometry(win)
+    if err != nil { return plan.Window{}, err }
+    extents, err := GetFrameExtents(win)
+    if err != nil { return plan.Window{}, err }
+
+    return plan.Window{
+        Frame:      frame,
+        Hints:      GetSizeHints(win),
+        Extents:    *extents,
+        Area:       UsableArea(win.X, frame),
+    }, nil
+}
 
-    err = MoveResize(right, bounds.X() + left_width, bounds.Y(), right_width, bounds.Height())
+func describeBoth(target, incoming *xwindow.Window) (t, i plan.Window, err error) {
+    t, err = Describe(target)
     if err != nil {
-        log.Printf("splitHorizontal: error configuring right: %v\n", err)
-        return err
+        log.Printf("describeBoth: error reading target: %v\n", err)
+        return
     }
-
-    err = MoveResize(left, bounds.X(), bounds.Y(), left_width, bounds.Height())
+    i, err = Describe(incoming)
     if err != nil {
-        log.Printf("splitHorizontal: error configuring left: %v\n", err)
-        return err
+        log.Printf("describeBoth: error reading incoming: %v\n", err)
     }
+    return
+}
 
-    // cool
-    return nil
+// turn a plan for "the target" and "the incoming window" into one for
+// these particular windows
+func resolve(p plan.Plan, target, incoming *xwindow.Window) Plan {
+    resolved := make(Plan, len(p))
+    for i, placement := range p {
+        win := target
+        if placement.Role == plan.Incoming {
+            win = incoming
+        }
+        resolved[i] = Placement{win, placement.Rect}
+    }
+    return resolved
 }
 
 // Exported split actions
 
 // Split the target window, putting the incoming window in the top half
 func SplitTop(target, incoming *xwindow.Window) error {
-    return splitVertical(target, incoming, true)
+    return execute(PlanSplitTop, target, incoming)
 }
 // Split the target window, putting the incoming window in the bottom half
 func SplitBottom(target, incoming *xwindow.Window) error {
-    return splitVertical(target, incoming, false)
+    return execute(PlanSplitBottom, target, incoming)
 }
 // Split the target window, putting the incoming window in the left half
 func SplitLeft(target, incoming *xwindow.Window) error {
-    return splitHorizontal(target, incoming, true)
+    return execute(PlanSplitLeft, target, incoming)
 }
 // Split the target window, putting the incoming window in the right half
 func SplitRight(target, incoming *xwindow.Window) error {
-    return splitHorizontal(target, incoming, false)
+    return execute(PlanSplitRight, target, incoming)
 }
 
-// Swap the position and size of the target and incoming windows
-func Swap(target, incoming *xwindow.Window) error {
-    // get bounds for both windows
-    target_bounds, err := target.DecorGeometry()
-    if err != nil {
-        log.Printf("Swap: error getting bounds of target: %v\n", err)
-        return err
-    }
-    incoming_bounds, err := incoming.DecorGeometry()
-    if err != nil {
-        log.Printf("Swap: error getting bounds of incoming: %v\n", err)
-        return err
-    }
+// Split the target window, putting the incoming window in the `dir` half
+func Split(target, incoming *xwindow.Window, dir Direction) error {
+    p, err := PlanSplit(target, incoming, dir)
+    if err != nil { return err }
+    return p.Apply()
+}
 
-    // configure windows, easy as pie!
-    err = MoveResize(target, incoming_bounds.X(), incoming_bounds.Y(), 
-        incoming_bounds.Width(), incoming_bounds.Height())
-    if err != nil {
-        log.Printf("Swap: error configuring target: %v\n", err)
-        return err
-    }
-    err = MoveResize(incoming, target_bounds.X(), target_bounds.Y(),
-        target_bounds.Width(), target_bounds.Height())
-    if err != nil {
-        log.Printf("Swap: error configuring incoming: %v\n", err)
-        return err
-    }
+// see Split
+func PlanSplit(target, incoming *xwindow.Window, dir Direction) (Plan, error) {
+    t, i, err := describeBoth(target, incoming)
+    if err != nil { return nil, err }
+    return resolve(plan.PlanSplit(t, i, dir), target, incoming), nil
+}
 
-    // cool
-    return nil
+// see SplitTop
+func PlanSplitTop(target, incoming *xwindow.Window) (Plan, error) {
+    return PlanSplit(target, incoming, Top)
+}
+// see SplitBottom
+func PlanSplitBottom(target, incoming *xwindow.Window) (Plan, error) {
+    return PlanSplit(target, incoming, Bottom)
+}
+// see SplitLeft
+func PlanSplitLeft(target, incoming *xwindow.Window) (Plan, error) {
+    return PlanSplit(target, incoming, Left)
+}
+// see SplitRight
+func PlanSplitRight(target, incoming *xwindow.Window) (Plan, error) {
+    return PlanSplit(target, incoming, Right)
 }
 
-type Direction uint8
-const (
-    Top Direction = 1 << iota
-    Right
-    Bottom
-    Left
-)
+// Swap the position and size of the target and incoming windows
+func Swap(target, incoming *xwindow.Window) error {
+    return execute(PlanSwap, target, incoming)
+}
 
-// lol a string method
-// TODO: support directions that have been masked together to form things like TopLeft, etc
-func (d Direction) String() string {
-    if d == Top { return "Top" }
-    if d == Right { return "Right" }
-    if d == Bottom { return "Bottom" }
-    if d == Left { return "Left" }
-    return "Unknown Direction"
-}
-
-func (d Direction) Opposite() Direction {
-    switch d {
-    case Top: return Bottom
-    case Bottom: return Top
-    case Left: return Right
-    case Right: return Left
-    }
-    log.Panic("Direction.Opposite: Unreachable")
-    return 0
+// see Swap
+func PlanSwap(target, incoming *xwindow.Window) (Plan, error) {
+    t, i, err := describeBoth(target, incoming)
+    if err != nil { return nil, err }
+    return resolve(plan.PlanSwap(t, i), target, incoming), nil
 }
 
+
 // Put the incoming window on the `dir` side of the target,
 // and transform the orthagonal dimension (eg, if `dir` is Up, then dim is `Width`
 // to be the same as the target's dimension
-// TODO: clip windows to display boundry
+//
+// The result is clipped to the usable area of the target's monitor; see
+// plan.PlanShove.
 func Shove(target, incoming *xwindow.Window, dir Direction) error {
-    // get geometries
-    i, err := incoming.DecorGeometry()
-    if err != nil { return err }
-
-    t, err := target.DecorGeometry()
+    p, err := PlanShove(target, incoming, dir)
     if err != nil { return err }
+    return p.Apply()
+}
 
-
-    // move in the correct direction
-    if dir == Top {
-        err := MoveResize(incoming, t.X(), t.Y() - i.Height(), t.Width(), i.Height())
-        if err != nil { return err }
-    }
-
-    if dir == Bottom {
-        err := MoveResize(incoming, t.X(), t.Y() + t.Height(), t.Width(), i.Height())
-        if err != nil { return err }
-    }
-
-    if dir == Left {
-        err := MoveResize(incoming, t.X() - i.Width(), t.Y(), i.Width(), t.Height())
-        if err != nil { return err }
-    }
-
-    if dir == Right {
-        err := MoveResize(incoming, t.X() + t.Width(), t.Y(), i.Width(), t.Height())
-        if err != nil { return err }
-    }
-
-    return nil
+// see Shove
+func PlanShove(target, incoming *xwindow.Window, dir Direction) (Plan, error) {
+    t, i, err := describeBoth(target, incoming)
+    if err != nil { return nil, err }
+    p, err := plan.PlanShove(t, i, dir)
+    if err != nil { return nil, err }
+    return resolve(p, target, incoming), nil
 }
 
 // see Shove
@@ -238,6 +241,18 @@ func ShoveBottom(t, i *xwindow.Window) error {
 func ShoveLeft(t, i *xwindow.Window) error {
     return Shove(t, i, Left)
 }
+func PlanShoveTop(t, i *xwindow.Window) (Plan, error) {
+    return PlanShove(t, i, Top)
+}
+func PlanShoveRight(t, i *xwindow.Window) (Plan, error) {
+    return PlanShove(t, i, Right)
+}
+func PlanShoveBottom(t, i *xwindow.Window) (Plan, error) {
+    return PlanShove(t, i, Bottom)
+}
+func PlanShoveLeft(t, i *xwindow.Window) (Plan, error) {
+    return PlanShove(t, i, Left)
+}
 
 
 // TODO: all of the above in a tiling context
@@ -0,0 +1,116 @@
+package wm
+
+/* frame.go
+   Work out how big a window's decorations are, so we can translate between
+   the frame geometry that layouts are done in and the client geometry that
+   window managers expect in move/resize requests.
+
+   xwindow.Window.DecorGeometry() walks up the window tree to the child of
+   the root window and assumes that is the frame. That's wrong under window
+   managers that wrap clients in more than one window, or whose outermost
+   window is bigger than the visible frame. So we ask the window manager:
+
+    1. read _NET_FRAME_EXTENTS from the client
+    2. if it isn't set, send _NET_REQUEST_FRAME_EXTENTS, and meanwhile
+       measure the client against its top-level ancestor
+
+   We never wait for the answer: lookups happen on the event loop, for every
+   window under the pointer. If the window manager does set the property,
+   the GeometryTracker sees the PropertyNotify and the next lookup reads it.
+   */
+import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil/ewmh"
+    "github.com/BurntSushi/xgbutil/xrect"
+    "github.com/BurntSushi/xgbutil/xwindow"
+
+    "github.com/justjake/j3/plan"
+
+    "sync"
+)
+
+// _NET_FRAME_EXTENTS, once we've read it. The tracker forgets a window's
+// entry when the property changes or the window is reparented. Measured
+// extents are never cached, so the real property wins as soon as it's set.
+var (
+    extentsLock  sync.Mutex
+    extentsCache = make(map[xproto.Window]*plan.Extents)
+    // windows we've sent _NET_REQUEST_FRAME_EXTENTS for, so we only ask once
+    extentsAsked = make(map[xproto.Window]bool)
+)
+
+// forget the cached extents for a window, eg because it was reparented
+func ForgetFrameExtents(win xproto.Window) {
+    extentsLock.Lock()
+    delete(extentsCache, win)
+    delete(extentsAsked, win)
+    extentsLock.Unlock()
+}
+
+// Get the frame extents of a client window, from _NET_FRAME_EXTENTS if the
+// window manager has set it, or by measuring parent windows if it hasn't.
+func GetFrameExtents(win *xwindow.Window) (*plan.Extents, error) {
+    extentsLock.Lock()
+    extents, ok := extentsCache[win.Id]
+    ask := !extentsAsked[win.Id]
+    extentsAsked[win.Id] = true
+    extentsLock.Unlock()
+    if ok { return extents, nil }
+
+    ext, err := ewmh.FrameExtentsGet(win.X, win.Id)
+    if err == nil {
+        extents = &plan.Extents{Left: ext.Left, Right: ext.Right, Top: ext.Top, Bottom: ext.Bottom}
+        extentsLock.Lock()
+        extentsCache[win.Id] = extents
+        extentsLock.Unlock()
+        return extents, nil
+    }
+
+    if ask {
+        log.Printf("GetFrameExtents: no _NET_FRAME_EXTENTS for %v (%v), asking for it and measuring parents meanwhile\n", win.Id, err)
+        // the answer, if any, arrives as a property change
+        ewmh.RequestFrameExtents(win.X, win.Id)
+    }
+    return measureFrameExtents(win)
+}
+
+// measure the client against its top-level ancestor, DecorGeometry style
+func measureFrameExtents(win *xwindow.Window) (*plan.Extents, error) {
+    decor, err := win.DecorGeometry()
+    if err != nil { return nil, err }
+    geom, err := win.Geometry()
+    if err != nil { return nil, err }
+    cx, cy, err := TranslateCoordinatesSync(win.X, win.Id, win.X.RootWin(), 0, 0)
+    if err != nil { return nil, err }
+
+    left := cx - decor.X()
+    top := cy - decor.Y()
+    return &plan.Extents{
+        Left:   left,
+        Right:  decor.Width() - geom.Width() - left,
+        Top:    top,
+        Bottom: decor.Height() - geom.Height() - top,
+    }, nil
+}
+
+// The geometry of a window including its decorations, in root window
+// coordinates. This replaces xwindow.Window.DecorGeometry for j3's purposes.
+func FrameGeometry(win *xwindow.Window) (xrect.Rect, error) {
+    geom, err := win.Geometry()
+    if err != nil { return nil, err }
+    return frameAround(win, geom)
+}
+
+// the frame geometry around a client whose plain geometry is `geom`
+func frameAround(win *xwindow.Window, geom xrect.Rect) (xrect.Rect, error) {
+    extents, err := GetFrameExtents(win)
+    if err != nil { return nil, err }
+
+    // geom.X/Y are relative to the client's parent, which is probably a
+    // frame window. We want root coordinates.
+    cx, cy, err := TranslateCoordinatesSync(win.X, win.Id, win.X.RootWin(), 0, 0)
+    if err != nil { return nil, err }
+
+    w, h := extents.FrameSize(geom.Width(), geom.Height())
+    return xrect.New(cx - extents.Left, cy - extents.Top, w, h), nil
+}
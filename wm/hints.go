@@ -0,0 +1,41 @@
+package wm
+
+/* hints.go
+   Read WM_NORMAL_HINTS size constraints from X. The layout math that uses
+   them lives in the plan package.
+   */
+import (
+    "github.com/BurntSushi/xgbutil/icccm"
+    "github.com/BurntSushi/xgbutil/xwindow"
+
+    "github.com/justjake/j3/plan"
+)
+
+// Read the WM_NORMAL_HINTS of a window. Windows without hints (or with
+// garbled ones) get plan.NoSizeHints, so this never returns nil.
+func GetSizeHints(win *xwindow.Window) *plan.SizeHints {
+    hints := plan.NoSizeHints()
+
+    nh, err := icccm.WmNormalHintsGet(win.X, win.Id)
+    if err != nil {
+        return hints
+    }
+
+    if nh.Flags & icccm.SizeHintPMinSize > 0 {
+        hints.MinWidth, hints.MinHeight = int(nh.MinWidth), int(nh.MinHeight)
+    }
+    if nh.Flags & icccm.SizeHintPMaxSize > 0 {
+        hints.MaxWidth, hints.MaxHeight = int(nh.MaxWidth), int(nh.MaxHeight)
+    }
+    if nh.Flags & icccm.SizeHintPBaseSize > 0 {
+        hints.BaseWidth, hints.BaseHeight = int(nh.BaseWidth), int(nh.BaseHeight)
+    } else {
+        // ICCCM: base size defaults to the min size
+        hints.BaseWidth, hints.BaseHeight = hints.MinWidth, hints.MinHeight
+    }
+    if nh.Flags & icccm.SizeHintPResizeInc > 0 {
+        if nh.WidthInc > 0 { hints.WidthInc = int(nh.WidthInc) }
+        if nh.HeightInc > 0 { hints.HeightInc = int(nh.HeightInc) }
+    }
+    return hints
+}
@@ -0,0 +1,323 @@
+package wm
+
+/* monitors.go
+   Multi-head awareness. _NET_WORKAREA is one rectangle per desktop, which
+   on a multi-monitor setup is usually the bounding box of every head, so
+   it happily lets windows straddle two monitors or hide under a panel that
+   only sits on one of them. Instead we ask RandR for the active CRTCs
+   (falling back to Xinerama, then to the root window) and shrink each head
+   by the struts of the panels on it.
+
+   Heads are cached until the screen is reconfigured or a strut might have
+   changed; see WatchHeads.
+   */
+import (
+    "github.com/BurntSushi/xgb/randr"
+    "github.com/BurntSushi/xgb/xinerama"
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/ewmh"
+    "github.com/BurntSushi/xgbutil/xevent"
+    "github.com/BurntSushi/xgbutil/xprop"
+    "github.com/BurntSushi/xgbutil/xrect"
+    "github.com/BurntSushi/xgbutil/xwindow"
+
+    "github.com/justjake/j3/plan"
+
+    "sync"
+)
+
+// One physical monitor
+type Head struct {
+    // the whole monitor, in root window coordinates
+    Rect   xrect.Rect
+    // the part of the monitor not covered by panels and docks
+    Usable xrect.Rect
+}
+
+var (
+    headsLock sync.Mutex
+    heads     []*Head
+    // which extensions we managed to initialize; set up on first use
+    extsOnce    sync.Once
+    hasRandr    bool
+    hasXinerama bool
+)
+
+func initExtensions(X *xgbutil.XUtil) {
+    extsOnce.Do(func() {
+        if err := randr.Init(X.Conn()); err == nil {
+            _, err = randr.QueryVersion(X.Conn(), 1, 3).Reply()
+            hasRandr = err == nil
+        }
+        if err := xinerama.Init(X.Conn()); err == nil {
+            hasXinerama = true
+        }
+        if !hasRandr {
+            log.Printf("initExtensions: RandR 1.3 unavailable, using Xinerama for monitor layout\n")
+        }
+    })
+}
+
+// All active heads, with their usable areas
+func Heads(X *xgbutil.XUtil) []*Head {
+    headsLock.Lock()
+    defer headsLock.Unlock()
+
+    if heads == nil {
+        heads = queryHeads(X)
+    }
+    return heads
+}
+
+// forget the cached heads, eg because a monitor was plugged in or a panel
+// changed its struts
+func ForgetHeads() {
+    headsLock.Lock()
+    heads = nil
+    headsLock.Unlock()
+}
+
+// Forget the cached heads whenever the screen is resized or rearranged
+// (including RandR changes to monitors that leave the screen size alone), or
+// the window manager updates the work area or client list (which is when
+// panels come and go).
+func WatchHeads(X *xgbutil.XUtil) error {
+    root := xwindow.New(X, X.RootWin())
+    err := root.Listen(xproto.EventMaskStructureNotify, xproto.EventMaskPropertyChange)
+    if err != nil { return err }
+
+    xevent.ConfigureNotifyFun(func(X *xgbutil.XUtil, ev xevent.ConfigureNotifyEvent) {
+        ForgetHeads()
+    }).Connect(X, root.Id)
+
+    xevent.PropertyNotifyFun(func(X *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
+        name, err := xprop.AtomName(X, ev.Atom)
+        if err != nil { return }
+        switch name {
+        case "_NET_WORKAREA", "_NET_CLIENT_LIST", "_NET_CURRENT_DESKTOP":
+            ForgetHeads()
+        }
+    }).Connect(X, root.Id)
+
+    // monitors can be rotated, moved or swapped without the root window
+    // changing size, and only RandR tells us about that
+    initExtensions(X)
+    if !hasRandr { return nil }
+    err = randr.SelectInputChecked(X.Conn(), root.Id,
+        randr.NotifyMaskScreenChange | randr.NotifyMaskCrtcChange | randr.NotifyMaskOutputChange).Check()
+    if err != nil { return err }
+
+    // xevent has no callbacks for extension events, so catch them on the
+    // way in. Returning false stops xevent from complaining about them.
+    xevent.HookFun(func(X *xgbutil.XUtil, ev interface{}) bool {
+        switch ev.(type) {
+        case randr.ScreenChangeNotifyEvent, randr.NotifyEvent:
+            ForgetHeads()
+            return false
+        }
+        return true
+    }).Connect(X)
+    return nil
+}
+
+// The head that `rect` is on: the one it overlaps the most, or if it's
+// entirely off screen, the closest one.
+func HeadFor(X *xgbutil.XUtil, rect xrect.Rect) *Head {
+    all := Heads(X)
+
+    var best *Head
+    best_area := 0
+    for _, head := range all {
+        if overlap, ok := plan.Intersect(rect, head.Rect); ok {
+            if area := overlap.Width() * overlap.Height(); area > best_area {
+                best, best_area = head, area
+            }
+        }
+    }
+    if best != nil {
+        return best
+    }
+
+    // off screen: go by distance between centers
+    cx, cy := rect.X() + rect.Width() / 2, rect.Y() + rect.Height() / 2
+    best_dist := -1
+    for _, head := range all {
+        hx := head.Rect.X() + head.Rect.Width() / 2
+        hy := head.Rect.Y() + head.Rect.Height() / 2
+        dist := (hx - cx) * (hx - cx) + (hy - cy) * (hy - cy)
+        if best_dist < 0 || dist < best_dist {
+            best, best_dist = head, dist
+        }
+    }
+    return best
+}
+
+// read the monitor layout and struts from the X server
+func queryHeads(X *xgbutil.XUtil) []*Head {
+    initExtensions(X)
+
+    var rects []xrect.Rect
+    var err error
+    if hasRandr {
+        rects, err = randrHeads(X)
+        if err != nil {
+            log.Printf("queryHeads: RandR failed, trying Xinerama: %v\n", err)
+        }
+    }
+    if len(rects) == 0 && hasXinerama {
+        rects, err = xineramaHeads(X)
+        if err != nil {
+            log.Printf("queryHeads: Xinerama failed, using the root window: %v\n", err)
+        }
+    }
+    if len(rects) == 0 {
+        rects = []xrect.Rect{xwindow.RootGeometry(X)}
+    }
+
+    usable := make([]xrect.Rect, len(rects))
+    for i, rect := range rects {
+        usable[i] = xrect.New(rect.X(), rect.Y(), rect.Width(), rect.Height())
+    }
+    applyStruts(X, usable)
+
+    // panels that aren't managed windows don't show up in the client list,
+    // but the window manager still counts them in _NET_WORKAREA
+    workarea := Workarea(X)
+    result := make([]*Head, len(rects))
+    for i := range rects {
+        result[i] = &Head{rects[i], plan.ClipRect(usable[i], workarea)}
+    }
+    return result
+}
+
+// the geometry of every enabled CRTC, skipping clones
+func randrHeads(X *xgbutil.XUtil) ([]xrect.Rect, error) {
+    res, err := randr.GetScreenResourcesCurrent(X.Conn(), X.RootWin()).Reply()
+    if err != nil { return nil, err }
+
+    var rects []xrect.Rect
+    for _, crtc := range res.Crtcs {
+        info, err := randr.GetCrtcInfo(X.Conn(), crtc, res.ConfigTimestamp).Reply()
+        if err != nil { return nil, err }
+        // CRTCs with no mode or no outputs are switched off
+        if info.Mode == 0 || info.NumOutputs == 0 || info.Width == 0 || info.Height == 0 {
+            continue
+        }
+        rect := xrect.New(int(info.X), int(info.Y), int(info.Width), int(info.Height))
+        if !containsRect(rects, rect) {
+            rects = append(rects, rect)
+        }
+    }
+    return rects, nil
+}
+
+// the geometry of every Xinerama screen, skipping clones
+func xineramaHeads(X *xgbutil.XUtil) ([]xrect.Rect, error) {
+    reply, err := xinerama.QueryScreens(X.Conn()).Reply()
+    if err != nil { return nil, err }
+
+    var rects []xrect.Rect
+    for _, info := range reply.ScreenInfo {
+        rect := xrect.New(int(info.XOrg), int(info.YOrg), int(info.Width), int(info.Height))
+        if !containsRect(rects, rect) {
+            rects = append(rects, rect)
+        }
+    }
+    return rects, nil
+}
+
+func containsRect(rects []xrect.Rect, rect xrect.Rect) bool {
+    for _, r := range rects {
+        if r.X() == rect.X() && r.Y() == rect.Y() &&
+            r.Width() == rect.Width() && r.Height() == rect.Height() {
+            return true
+        }
+    }
+    return false
+}
+
+// A strut: space a panel reserves along one edge of the root window.
+// Start and End bound the panel along that edge, inclusive.
+type strut struct {
+    Side        Direction
+    Size        int
+    Start, End  int
+}
+
+// the struts set on a window, from _NET_WM_STRUT_PARTIAL or the older
+// _NET_WM_STRUT, which reserves the whole length of each edge
+func windowStruts(X *xgbutil.XUtil, win xproto.Window, rw, rh int) []strut {
+    if s, err := ewmh.WmStrutPartialGet(X, win); err == nil {
+        return []strut{
+            {Top, int(s.Top), int(s.TopStartX), int(s.TopEndX)},
+            {Bottom, int(s.Bottom), int(s.BottomStartX), int(s.BottomEndX)},
+            {Left, int(s.Left), int(s.LeftStartY), int(s.LeftEndY)},
+            {Right, int(s.Right), int(s.RightStartY), int(s.RightEndY)},
+        }
+    }
+    if s, err := ewmh.WmStrutGet(X, win); err == nil {
+        return []strut{
+            {Top, int(s.Top), 0, rw - 1},
+            {Bottom, int(s.Bottom), 0, rw - 1},
+            {Left, int(s.Left), 0, rh - 1},
+            {Right, int(s.Right), 0, rh - 1},
+        }
+    }
+    return nil
+}
+
+// Shrink `rects` by the struts of every managed window. We don't use
+// xrect.ApplyStrut: it only handles one edge per window, and its unsigned
+// math goes wrong for heads that a strut doesn't reach.
+func applyStruts(X *xgbutil.XUtil, rects []xrect.Rect) {
+    clients, err := ewmh.ClientListGet(X)
+    if err != nil { return }
+
+    root := xwindow.RootGeometry(X)
+    rw, rh := root.Width(), root.Height()
+    for _, client := range clients {
+        for _, s := range windowStruts(X, client, rw, rh) {
+            if s.Size <= 0 { continue }
+            if s.Start == 0 && s.End == 0 {
+                // some panels leave the span unset
+                if s.Side == Top || s.Side == Bottom {
+                    s.End = rw - 1
+                } else {
+                    s.End = rh - 1
+                }
+            }
+            for _, rect := range rects {
+                applyStrut(rect, s, rw, rh)
+            }
+        }
+    }
+}
+
+// shrink `rect` to get it out from under strut `s`
+func applyStrut(rect xrect.Rect, s strut, rw, rh int) {
+    x1, y1 := rect.X(), rect.Y()
+    x2, y2 := x1 + rect.Width(), y1 + rect.Height()
+
+    switch s.Side {
+    case Top, Bottom:
+        if s.End < x1 || s.Start >= x2 { return }
+    case Left, Right:
+        if s.End < y1 || s.Start >= y2 { return }
+    }
+
+    switch s.Side {
+    case Top:    y1 = imax(y1, s.Size)
+    case Bottom: y2 = imin(y2, rh - s.Size)
+    case Left:   x1 = imax(x1, s.Size)
+    case Right:  x2 = imin(x2, rw - s.Size)
+    }
+    if x2 <= x1 || y2 <= y1 {
+        // a strut covering a whole head is nonsense; ignore it
+        return
+    }
+    rect.XSet(x1)
+    rect.YSet(y1)
+    rect.WidthSet(x2 - x1)
+    rect.HeightSet(y2 - y1)
+}
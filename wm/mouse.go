@@ -15,6 +15,7 @@ import (
     "github.com/BurntSushi/xgb/xproto"
     "github.com/BurntSushi/xgbutil"
     "github.com/BurntSushi/xgbutil/ewmh"
+    "github.com/BurntSushi/xgbutil/xwindow"
 
     "errors"
     "fmt"
@@ -78,6 +79,35 @@ func FindManagedWindowUnderMouse(X *xgbutil.XUtil) (xproto.Window, error) {
     return 0, errors.New("FindUnderMouse: no EWMH window found under mouse")
 }
 
+// Every visible managed window whose frame contains the point x, y in root
+// coordinates, topmost first. Unlike FindManagedWindowUnderMouse, this
+// includes the windows hidden behind the top one.
+func ManagedWindowsAt(X *xgbutil.XUtil, x, y int) ([]xproto.Window, error) {
+    clients, err := stackedClients(X)
+    if err != nil {
+        return nil, fmt.Errorf("ManagedWindowsAt: could not retrieve EWHM client list: %v", err)
+    }
+    desk, err := ewmh.CurrentDesktopGet(X)
+    if err != nil { desk = 0 }
+
+    under := []xproto.Window{}
+    // the stacking list goes bottom to top
+    for i := len(clients) - 1; i >= 0; i-- {
+        id := clients[i]
+        if !visible(X, id, desk) { continue }
+        frame, err := FrameGeometry(xwindow.New(X, id))
+        if err != nil {
+            log.Printf("ManagedWindowsAt: couldn't get geometry for %v: %v\n", id, err)
+            continue
+        }
+        if x >= frame.X() && x < frame.X() + frame.Width() &&
+            y >= frame.Y() && y < frame.Y() + frame.Height() {
+            under = append(under, id)
+        }
+    }
+    return under, nil
+}
+
 func FindWindowUnderMouse(X *xgbutil.XUtil, orig_window *xproto.Window) (xproto.Window, error) {
     var cur_window xproto.Window = 0
     for {
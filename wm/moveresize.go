@@ -1,6 +1,8 @@
 package wm
 
 import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil/ewmh"
     "github.com/BurntSushi/xgbutil/xwindow"
     "github.com/BurntSushi/xgbutil/xrect"
 
@@ -40,7 +42,7 @@ type GeometryUpdateTester func(*xwindow.Window) (bool, error)
 // Use with PollFor.
 func DecorDiffers(oldDecor xrect.Rect) GeometryUpdateTester {
     return func(win *xwindow.Window) (bool, error) {
-        newDecor, err := win.DecorGeometry()
+        newDecor, _, err := Geometries(win)
         if err != nil { return false, err }
         return (!util.RectEquals(oldDecor, newDecor)), nil
     }
@@ -50,12 +52,36 @@ func DecorDiffers(oldDecor xrect.Rect) GeometryUpdateTester {
 // Use with PollFor.
 func GeometryDiffers(oldGeom xrect.Rect) GeometryUpdateTester {
     return func(win *xwindow.Window) (bool, error) {
-        newGeom, err := win.Geometry()
+        _, newGeom, err := Geometries(win)
         if err != nil { return false, err }
         return (!util.RectEquals(oldGeom, newGeom)), nil
     }
 }
 
+// Returns True once either the window's frame or its client geometry
+// changes. Use with PollFor when a request may only move the window, or
+// only resize it.
+func GeometryChanged(oldDecor, oldGeom xrect.Rect) GeometryUpdateTester {
+    return func(win *xwindow.Window) (bool, error) {
+        newDecor, newGeom, err := Geometries(win)
+        if err != nil { return false, err }
+        return !util.RectEquals(oldDecor, newDecor) || !util.RectEquals(oldGeom, newGeom), nil
+    }
+}
+
+// run each geometry test predicate once, returning true if they all pass
+func runTesters(win *xwindow.Window, changes []GeometryUpdateTester) (bool, error) {
+    should_exit := true
+    for i, pred := range changes {
+        exit, err := pred(win)
+        if err != nil {
+            return false, fmt.Errorf("PollFor: error in predicate %d: %v", i, err)
+        }
+        should_exit = should_exit && exit
+    }
+    return should_exit, nil
+}
+
 
 // The previous PollForGeometryUpdate function and friends were very complex, and a bit brittle
 // PollFor and the GeometryUpdateTester generator functions GeometryDiffers and DecorDiffers
@@ -64,7 +90,15 @@ func GeometryDiffers(oldGeom xrect.Rect) GeometryUpdateTester {
 // PollFor runs each GeometryUpdateTester in order until all return true *on the same run*. 
 // If any GeometryUpdateTester throws an error, PollFor stops and returns that error.
 // On a successful polling, PollFor returns 'nil' as its error
+//
+// When the geometry Tracker is running, PollFor doesn't poll at all: it
+// re-runs the predicates against the Tracker's cache each time a
+// ConfigureNotify arrives for the window.
 func PollForTimeout(win *xwindow.Window, timeout time.Duration, changes ...GeometryUpdateTester) error {
+    if Tracker != nil {
+        return Tracker.WaitFor(win, timeout, changes...)
+    }
+
     timeout_channel := time.After(timeout)
 
     for {
@@ -72,17 +106,11 @@ func PollForTimeout(win *xwindow.Window, timeout time.Duration, changes ...Geome
         case <-timeout_channel:
             return &TimeoutError{"PollFor", timeout}
         default:
-            // run each geometry test predicate
-            should_exit := true
-            for i, pred := range changes {
-                exit, err := pred(win)
-                if err != nil {
-                    return fmt.Errorf("PollFor: error in predicate %d: %v", i, err)
-                }
-                should_exit = should_exit && exit
+            // run each geometry test predicate, and exit when all pass
+            should_exit, err := runTesters(win, changes)
+            if err != nil {
+                return err
             }
-
-            // exit when all pass
             if should_exit {
                 return nil
             }
@@ -164,40 +192,118 @@ func Move(win *xwindow.Window, x, y int) error {
     return nil
 }
 
-// same as above, but moveresize instead of just move at the first step,
-// then resize to the provided w/h instead of a snapshotted one
-// this implementation differs from Move in that it makes no effort to be end-synchronous
-// This function waits only on the window's inner geometry resizing, not on actual movement occuring
-func MoveResize(win *xwindow.Window, x, y, width, height int) error {
+// Move and resize a window's frame to x, y, width, height, like FrameGeometry.
+// We subtract the frame extents ourselves so the window manager is asked
+// for the exact client size up front.
+//
+// `gravity` is the point of the frame that stays put if the window manager
+// won't give us exactly that size, eg because of size hints: NorthWest keeps
+// the top left corner at x, y, SouthEast keeps the bottom right corner at
+// x + width, y + height, and so on. See ResizeGravity.
+//
+// When the window manager supports _NET_MOVERESIZE_WINDOW, the move and the
+// resize happen in one request. Otherwise we fall back to moveResizeTwoStep,
+// which can't ask for a gravity.
+// Either way, this waits only on the window's geometry changing, not on it
+// reaching exactly what we asked for: size hints may have other ideas.
+func MoveResize(win *xwindow.Window, x, y, width, height int, gravity int) error {
     // snapshot window dimensions
-    base, err := win.Geometry()
+    decor, base, err := Geometries(win)
+    if err != nil { return err }
+
+    // nothing to do, and nothing would change for us to wait on
+    if decor.X() == x && decor.Y() == y && decor.Width() == width && decor.Height() == height {
+        return nil
+    }
+
+    extents, err := GetFrameExtents(win)
+    if err != nil { return err }
+    client_w, client_h := extents.ClientSize(width, height)
+
+    if !Supported(win.X, "_NET_MOVERESIZE_WINDOW") {
+        return moveResizeTwoStep(win, base, x, y, client_w, client_h)
+    }
+
+    // With an explicit gravity the window manager finds the gravity's
+    // reference point on the client we ask for, and puts the same point of
+    // the frame there. So we ask for a client with that point where we want
+    // the frame's. Without an explicit gravity it uses the client's own
+    // win_gravity, and a terminal asking for StaticGravity would have us
+    // placing the client instead of the frame.
+    // Source 2 means "pager or other tool": we speak for the user.
+    cx, cy := gravityOrigin(gravity, x, y, width, height, client_w, client_h)
+    err = ewmh.MoveresizeWindowExtra(win.X, win.Id, cx, cy, client_w, client_h,
+        gravity, 2, true, true)
     if err != nil { return err }
 
+    return PollFor(win, GeometryChanged(decor, base))
+}
+
+// The gravity that keeps a window's frame flush against the edge opposite
+// `dir` when resizing its `dir` edge(s): East when dragging the left edge,
+// South when dragging the top, SouthEast for both, NorthWest otherwise.
+func ResizeGravity(dir Direction) int {
+    east, south := dir & Left != 0, dir & Top != 0
+    switch {
+    case east && south: return xproto.GravitySouthEast
+    case east:          return xproto.GravityEast
+    case south:         return xproto.GravitySouth
+    }
+    return xproto.GravityNorthWest
+}
+
+// where to put the top left corner of a client_w x client_h client so that
+// the point `gravity` names is at the same place as on the frame x, y,
+// width, height
+func gravityOrigin(gravity, x, y, width, height, client_w, client_h int) (int, int) {
+    dx, dy := width - client_w, height - client_h
+    switch gravity {
+    case xproto.GravityNorth, xproto.GravityCenter, xproto.GravitySouth:
+        x += dx / 2
+    case xproto.GravityNorthEast, xproto.GravityEast, xproto.GravitySouthEast:
+        x += dx
+    }
+    switch gravity {
+    case xproto.GravityWest, xproto.GravityCenter, xproto.GravityEast:
+        y += dy / 2
+    case xproto.GravitySouthWest, xproto.GravitySouth, xproto.GravitySouthEast:
+        y += dy
+    }
+    return x, y
+}
+
+// Fallback for window managers without _NET_MOVERESIZE_WINDOW: send a plain
+// ConfigureWindow for the client, which the window manager intercepts, then
+// resize a second time if the first attempt came out the wrong size.
+func moveResizeTwoStep(win *xwindow.Window, base xrect.Rect, x, y, client_w, client_h int) error {
     // move window then wait...
-    err = win.WMMoveResize(x, y, width, height)
-    if err != nil {return err}
-    err = PollFor(win, GeometryDiffers(base))
+    win.MoveResize(x, y, client_w, client_h)
+    err := PollFor(win, GeometryDiffers(base))
     if err != nil {return err}
 
     // check that the new geometry is what we requested
     // this may be inadvisable: what about window hints?
-    geom, err := win.Geometry()
+    _, geom, err := Geometries(win)
     if err != nil {return err}
-    if geom.Width() != width || geom.Height() != height {
+    if geom.Width() != client_w || geom.Height() != client_h {
         // something derped! resize to make it right!
         // if window hints constrained us, this won't upset them
         log.Println("MoveResize: resizing again after incorrect new dimensions")
-        err = win.WMResize(width, height)
-        if err != nil {return err}
+        win.Resize(client_w, client_h)
     }
 
     return nil
 }
 
+// the frame and plain geometries of a window. These come from the
+// Tracker's cache when it is running, and straight from X otherwise.
 func Geometries(win *xwindow.Window) (xrect.Rect, xrect.Rect, error) {
-    decor, err := win.DecorGeometry()
-    if err != nil { return nil, nil, err }
+    if Tracker != nil {
+        return Tracker.Geometries(win)
+    }
     base, err := win.Geometry()
     if err != nil { return nil, nil, err }
+    decor, err := frameAround(win, base)
+    if err != nil { return nil, nil, err }
     return decor, base, nil
 }
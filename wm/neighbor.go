@@ -0,0 +1,86 @@
+package wm
+
+/* neighbor.go
+   Find the focused window, and the window next to it in some direction,
+   for the keyboard versions of the window actions.
+   */
+import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/ewmh"
+    "github.com/BurntSushi/xgbutil/xrect"
+    "github.com/BurntSushi/xgbutil/xwindow"
+
+    "github.com/justjake/j3/plan"
+
+    "errors"
+    "fmt"
+)
+
+// windows on every desktop have this _NET_WM_DESKTOP
+const allDesktops = 0xFFFFFFFF
+
+// the window that has focus, from _NET_ACTIVE_WINDOW
+func ActiveWindow(X *xgbutil.XUtil) (*xwindow.Window, error) {
+    active, err := ewmh.ActiveWindowGet(X)
+    if err != nil { return nil, err }
+    if active == 0 {
+        return nil, errors.New("ActiveWindow: no window has focus")
+    }
+    return xwindow.New(X, active), nil
+}
+
+// the managed windows, bottom to top if the window manager publishes
+// _NET_CLIENT_LIST_STACKING
+func stackedClients(X *xgbutil.XUtil) ([]xproto.Window, error) {
+    clients, err := ewmh.ClientListStackingGet(X)
+    if err == nil { return clients, nil }
+    return ewmh.ClientListGet(X)
+}
+
+// true if `win` is where the user can see it: on the current desktop, and
+// not minimized
+func visible(X *xgbutil.XUtil, win xproto.Window, desk uint) bool {
+    if on, err := ewmh.WmDesktopGet(X, win); err == nil && on != desk && on != allDesktops {
+        return false
+    }
+    states, _ := ewmh.WmStateGet(X, win)
+    for _, state := range states {
+        if state == "_NET_WM_STATE_HIDDEN" { return false }
+    }
+    return true
+}
+
+// Find the visible window next to `win` in direction `dir`; see
+// plan.Neighbor for what "next to" means
+func FindNeighbor(win *xwindow.Window, dir Direction, epsilon int) (*xwindow.Window, error) {
+    X := win.X
+    from, err := FrameGeometry(win)
+    if err != nil { return nil, err }
+
+    clients, err := stackedClients(X)
+    if err != nil {
+        return nil, fmt.Errorf("FindNeighbor: could not retrieve EWMH client list: %v", err)
+    }
+    desk, err := ewmh.CurrentDesktopGet(X)
+    if err != nil { desk = 0 }
+
+    ids := []xproto.Window{}
+    frames := []xrect.Rect{}
+    for _, id := range clients {
+        if id == win.Id || !visible(X, id, desk) { continue }
+        frame, err := FrameGeometry(xwindow.New(X, id))
+        if err != nil {
+            log.Printf("FindNeighbor: couldn't get geometry for %v: %v\n", id, err)
+            continue
+        }
+        ids = append(ids, id)
+        frames = append(frames, frame)
+    }
+
+    i, ok := plan.Neighbor(from, frames, dir, epsilon)
+    if !ok {
+        return nil, fmt.Errorf("FindNeighbor: no window on the %v side of %v", dir, win.Id)
+    }
+    return xwindow.New(X, ids[i]), nil
+}
@@ -0,0 +1,106 @@
+package wm
+
+/* queue.go
+   Per-window resize locks. Window managers like Fluxbox fall over when we
+   send a window a new geometry before they finish applying the last one,
+   and mouse motion events arrive much faster than that.
+
+   A ConfigureQueue lets one configure job run per window at a time. Jobs
+   submitted while one is in flight don't pile up: each replaces the last,
+   so when the window manager catches up we jump straight to the newest
+   target geometry.
+   */
+import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil/xwindow"
+
+    "sync"
+)
+
+// some blocking work that configures a window, like MoveResize
+type ConfigureJob func() error
+
+type ConfigureQueue struct {
+    lock    sync.Mutex
+    windows map[xproto.Window]*queuedWindow
+}
+
+// a window with a job in flight
+type queuedWindow struct {
+    // the newest job submitted since the in-flight job started, or nil
+    pending ConfigureJob
+    // closed once the window has no jobs left
+    done    chan struct{}
+}
+
+func NewConfigureQueue() *ConfigureQueue {
+    return &ConfigureQueue{windows: make(map[xproto.Window]*queuedWindow)}
+}
+
+// the queue used by MoveResizeLatest
+var Configures = NewConfigureQueue()
+
+// Run `job` for `win` in the background. If a job for `win` is already
+// running, `job` waits for it to finish, replacing any job that was
+// already waiting. Errors are logged, since nobody is around to handle them.
+func (q *ConfigureQueue) Submit(win xproto.Window, job ConfigureJob) {
+    q.lock.Lock()
+    defer q.lock.Unlock()
+
+    if queued, busy := q.windows[win]; busy {
+        queued.pending = job
+        return
+    }
+
+    queued := &queuedWindow{nil, make(chan struct{})}
+    q.windows[win] = queued
+    go q.run(win, queued, job)
+}
+
+// run jobs for a window until none are left
+func (q *ConfigureQueue) run(win xproto.Window, queued *queuedWindow, job ConfigureJob) {
+    for job != nil {
+        if err := job(); err != nil {
+            log.Printf("ConfigureQueue: job for window %v failed: %v\n", win, err)
+        }
+
+        q.lock.Lock()
+        job = queued.pending
+        queued.pending = nil
+        if job == nil {
+            delete(q.windows, win)
+            close(queued.done)
+        }
+        q.lock.Unlock()
+    }
+}
+
+// true if `win` has a job in flight
+func (q *ConfigureQueue) Busy(win xproto.Window) bool {
+    q.lock.Lock()
+    defer q.lock.Unlock()
+    _, busy := q.windows[win]
+    return busy
+}
+
+// block until `win` has no jobs in flight or waiting. Never call this from
+// an X event handler: jobs wait on events that only the event loop delivers.
+// Submit the work that needs to come after as a job instead.
+func (q *ConfigureQueue) Wait(win xproto.Window) {
+    q.lock.Lock()
+    queued, busy := q.windows[win]
+    q.lock.Unlock()
+
+    if busy {
+        <-queued.done
+    }
+}
+
+// MoveResize in the background, through the Configures queue. If the window
+// is still busy with an earlier request, only the newest geometry is applied
+// once the window manager is done.
+func MoveResizeLatest(win *xwindow.Window, x, y, width, height int, gravity int) {
+    Configures.Submit(win.Id, func() error {
+        return MoveResize(win, x, y, width, height, gravity)
+    })
+}
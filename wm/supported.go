@@ -0,0 +1,44 @@
+package wm
+
+/* supported.go
+   Keep track of which EWMH hints the window manager advertises in
+   _NET_SUPPORTED, so we can use the nice ones when they're around and fall
+   back to older tricks when they aren't.
+   */
+import (
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/ewmh"
+
+    "sync"
+)
+
+var (
+    supportedLock sync.Mutex
+    supported     map[string]bool
+)
+
+// true if the window manager lists `hint` (eg "_NET_MOVERESIZE_WINDOW") in
+// _NET_SUPPORTED. The list is read once, the first time we ask.
+func Supported(X *xgbutil.XUtil, hint string) bool {
+    supportedLock.Lock()
+    defer supportedLock.Unlock()
+
+    if supported == nil {
+        supported = make(map[string]bool)
+        hints, err := ewmh.SupportedGet(X)
+        if err != nil {
+            log.Printf("Supported: couldn't read _NET_SUPPORTED, assuming nothing: %v\n", err)
+        }
+        for _, name := range hints {
+            supported[name] = true
+        }
+    }
+    return supported[hint]
+}
+
+// forget what the window manager supports, eg because it was replaced
+func ForgetSupported() {
+    supportedLock.Lock()
+    supported = nil
+    supportedLock.Unlock()
+}
@@ -0,0 +1,255 @@
+package wm
+
+/* tracker.go
+   Event-driven geometry tracking. Instead of asking X for a window's
+   geometry every millisecond until it changes, we select StructureNotify on
+   managed windows and their frames, cache their geometry whenever a
+   ConfigureNotify arrives, and wake up anyone waiting on that window.
+   Frames are only watched for events; the decorated geometry itself comes
+   from FrameGeometry's frame extents.
+
+   ConfigureNotify events are delivered by the xevent main loop, so waiting
+   on the tracker from inside an event handler would just sit out the whole
+   timeout. Run actions that wait on geometry changes in the background,
+   through a ConfigureQueue.
+   */
+import (
+    "github.com/BurntSushi/xgb/xproto"
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/xevent"
+    "github.com/BurntSushi/xgbutil/xprop"
+    "github.com/BurntSushi/xgbutil/xrect"
+    "github.com/BurntSushi/xgbutil/xwindow"
+
+    "sync"
+    "time"
+)
+
+// the tracker used by Geometries and PollFor. nil until StartTracking is
+// called, in which case we fall back to polling.
+var Tracker *GeometryTracker
+
+type GeometryTracker struct {
+    X       *xgbutil.XUtil
+    lock    sync.Mutex
+    // held while starting to track a window, so we never track one twice
+    trackLock sync.Mutex
+    // tracked client windows
+    windows map[xproto.Window]*trackedWindow
+    // frame window -> the client it decorates
+    frames  map[xproto.Window]xproto.Window
+}
+
+// cached state for one client window
+type trackedWindow struct {
+    win     *xwindow.Window
+    frame   xproto.Window
+    decor   xrect.Rect
+    geom    xrect.Rect
+    // closed (and replaced) every time the geometry cache is updated
+    changed chan struct{}
+}
+
+// Create the global geometry tracker. Windows are tracked lazily, the first
+// time we ask for their geometry with Geometries.
+func StartTracking(X *xgbutil.XUtil) *GeometryTracker {
+    Tracker = &GeometryTracker{
+        X:          X,
+        windows:    make(map[xproto.Window]*trackedWindow),
+        frames:     make(map[xproto.Window]xproto.Window),
+    }
+    return Tracker
+}
+
+// the top-level ancestor of a window, ie the window manager's frame,
+// or the window itself if it isn't reparented
+func frameOf(win *xwindow.Window) (xproto.Window, error) {
+    frame := win
+    for {
+        parent, err := frame.Parent()
+        if err != nil { return 0, err }
+        if parent.Id == win.X.RootWin() {
+            return frame.Id, nil
+        }
+        frame = parent
+    }
+}
+
+// start tracking `win` if we aren't already, and return its cache entry
+func (t *GeometryTracker) track(win *xwindow.Window) (*trackedWindow, error) {
+    t.trackLock.Lock()
+    defer t.trackLock.Unlock()
+
+    t.lock.Lock()
+    tracked, ok := t.windows[win.Id]
+    t.lock.Unlock()
+    if ok { return tracked, nil }
+
+    tracked = &trackedWindow{win: win, changed: make(chan struct{})}
+
+    // select events before reading the geometry, so we can't miss a change
+    // that happens in between
+    err := win.Listen(xproto.EventMaskStructureNotify, xproto.EventMaskPropertyChange)
+    if err != nil { return nil, err }
+    xevent.ConfigureNotifyFun(func(X *xgbutil.XUtil, ev xevent.ConfigureNotifyEvent) {
+        t.refresh(win.Id)
+    }).Connect(t.X, win.Id)
+    xevent.ReparentNotifyFun(func(X *xgbutil.XUtil, ev xevent.ReparentNotifyEvent) {
+        // new parents mean new decorations
+        ForgetFrameExtents(win.Id)
+        t.reframe(win.Id)
+    }).Connect(t.X, win.Id)
+    xevent.PropertyNotifyFun(func(X *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
+        name, err := xprop.AtomName(X, ev.Atom)
+        if err != nil || name != "_NET_FRAME_EXTENTS" { return }
+        // the window manager answered _NET_REQUEST_FRAME_EXTENTS, or changed
+        // the decorations, eg on fullscreen
+        ForgetFrameExtents(win.Id)
+        t.refresh(win.Id)
+    }).Connect(t.X, win.Id)
+    xevent.DestroyNotifyFun(func(X *xgbutil.XUtil, ev xevent.DestroyNotifyEvent) {
+        t.forget(win.Id)
+        ForgetFrameExtents(win.Id)
+    }).Connect(t.X, win.Id)
+
+    t.lock.Lock()
+    t.windows[win.Id] = tracked
+    t.lock.Unlock()
+
+    err = t.reframe(win.Id)
+    if err != nil {
+        t.forget(win.Id)
+        return nil, err
+    }
+    return tracked, nil
+}
+
+// (re)discover the frame of a tracked window, listen to it, and refresh
+// the cache. Called when we start tracking, and when the window is reparented.
+func (t *GeometryTracker) reframe(id xproto.Window) error {
+    t.lock.Lock()
+    tracked, ok := t.windows[id]
+    t.lock.Unlock()
+    if !ok { return nil }
+
+    frame, err := frameOf(tracked.win)
+    if err != nil { return err }
+
+    if frame != tracked.frame {
+        t.lock.Lock()
+        old_frame := tracked.frame
+        if old_frame != 0 {
+            delete(t.frames, old_frame)
+        }
+        tracked.frame = frame
+        t.frames[frame] = id
+        t.lock.Unlock()
+
+        // stop watching the old frame, if it was one. If it's been
+        // destroyed already there's no event mask left to clear, and the
+        // error doesn't matter
+        if old_frame != 0 && old_frame != id {
+            xevent.Detach(t.X, old_frame)
+            xwindow.New(t.X, old_frame).Listen()
+        }
+
+        if frame != id {
+            frame_win := xwindow.New(t.X, frame)
+            // the frame belongs to the window manager, but event masks are
+            // per-client, so listening to it doesn't get in the WM's way
+            err = frame_win.Listen(xproto.EventMaskStructureNotify)
+            if err != nil { return err }
+            xevent.ConfigureNotifyFun(func(X *xgbutil.XUtil, ev xevent.ConfigureNotifyEvent) {
+                t.lock.Lock()
+                client, ok := t.frames[frame]
+                t.lock.Unlock()
+                if ok {
+                    t.refresh(client)
+                }
+            }).Connect(t.X, frame)
+        }
+    }
+
+    return t.refresh(id)
+}
+
+// re-read the geometry of a tracked window from X, and wake up waiters
+func (t *GeometryTracker) refresh(id xproto.Window) error {
+    t.lock.Lock()
+    tracked, ok := t.windows[id]
+    t.lock.Unlock()
+    if !ok { return nil }
+
+    geom, err := tracked.win.Geometry()
+    if err != nil { return err }
+    decor, err := frameAround(tracked.win, geom)
+    if err != nil { return err }
+
+    t.lock.Lock()
+    tracked.decor, tracked.geom = decor, geom
+    close(tracked.changed)
+    tracked.changed = make(chan struct{})
+    t.lock.Unlock()
+    return nil
+}
+
+// stop tracking a window, eg because it was destroyed
+func (t *GeometryTracker) forget(id xproto.Window) {
+    t.lock.Lock()
+    tracked, ok := t.windows[id]
+    if ok {
+        delete(t.windows, id)
+        delete(t.frames, tracked.frame)
+    }
+    t.lock.Unlock()
+
+    if ok {
+        xevent.Detach(t.X, id)
+        if tracked.frame != id {
+            xevent.Detach(t.X, tracked.frame)
+        }
+    }
+}
+
+// the cached decorated and plain geometries of `win`, which starts being
+// tracked if it wasn't already
+func (t *GeometryTracker) Geometries(win *xwindow.Window) (xrect.Rect, xrect.Rect, error) {
+    tracked, err := t.track(win)
+    if err != nil { return nil, nil, err }
+
+    t.lock.Lock()
+    defer t.lock.Unlock()
+    return tracked.decor, tracked.geom, nil
+}
+
+// Wait until every GeometryUpdateTester passes against the cached geometry
+// of `win`, re-testing each time a ConfigureNotify updates the cache.
+// Just before giving up, we re-read the geometry from X once, in case we
+// are running inside the event loop and never saw the events.
+func (t *GeometryTracker) WaitFor(win *xwindow.Window, timeout time.Duration, changes ...GeometryUpdateTester) error {
+    tracked, err := t.track(win)
+    if err != nil { return err }
+
+    deadline := time.After(timeout)
+    for {
+        // grab the channel before testing, so an update that happens
+        // while we test still wakes us up
+        t.lock.Lock()
+        changed := tracked.changed
+        t.lock.Unlock()
+
+        done, err := runTesters(win, changes)
+        if err != nil || done { return err }
+
+        select {
+        case <-changed:
+            // test again
+        case <-deadline:
+            err = t.refresh(win.Id)
+            if err != nil { return err }
+            done, err := runTesters(win, changes)
+            if err != nil || done { return err }
+            return &TimeoutError{"PollFor", timeout}
+        }
+    }
+}
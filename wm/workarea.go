@@ -0,0 +1,46 @@
+package wm
+
+/* workarea.go
+   Keep window actions on screen. The window manager publishes the part of
+   each desktop that isn't covered by panels and docks in _NET_WORKAREA;
+   we clip layout results to it, and to the monitor the layout is on
+   (see monitors.go).
+   */
+import (
+    "github.com/BurntSushi/xgbutil"
+    "github.com/BurntSushi/xgbutil/ewmh"
+    "github.com/BurntSushi/xgbutil/xrect"
+    "github.com/BurntSushi/xgbutil/xwindow"
+)
+
+// the usable area of the current desktop, from _NET_WORKAREA.
+// If the window manager doesn't set it, the whole root window is usable.
+func Workarea(X *xgbutil.XUtil) xrect.Rect {
+    areas, err := ewmh.WorkareaGet(X)
+    if err != nil || len(areas) == 0 {
+        return xwindow.RootGeometry(X)
+    }
+
+    desk, err := ewmh.CurrentDesktopGet(X)
+    if err != nil || int(desk) >= len(areas) {
+        desk = 0
+    }
+    area := areas[desk]
+    return xrect.New(area.X, area.Y, int(area.Width), int(area.Height))
+}
+
+// the space that window actions around `rect` may use: the usable part of
+// the monitor `rect` is (mostly) on, so results never straddle two heads
+func UsableArea(X *xgbutil.XUtil, rect xrect.Rect) xrect.Rect {
+    return HeadFor(X, rect).Usable
+}
+
+func imin(a, b int) int {
+    if a < b { return a }
+    return b
+}
+
+func imax(a, b int) int {
+    if a > b { return a }
+    return b
+}